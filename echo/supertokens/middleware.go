@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
+)
+
+// Middleware for verifying and refreshing session.
+func Middleware(condition ...bool) echo.MiddlewareFunc {
+	options := supertokens.MiddlewareOptions{}
+	if len(condition) == 1 {
+		options.AntiCsrf = &condition[0]
+	}
+	return MiddlewareWithOptions(options)
+}
+
+// MiddlewareWithOptions for verifying and refreshing session. The next handler is not called if there is an error,
+// which is answered by options.ErrorHandler
+func MiddlewareWithOptions(options supertokens.MiddlewareOptions) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var nextError error
+			handler := supertokens.MiddlewareWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the request carries the session in its context, for handlers that use supertokens.GetSessionFromRequest
+				c.SetRequest(r)
+				actualSession := supertokens.GetSessionFromRequest(r)
+				if actualSession != nil {
+					session := Session{
+						actualSession: actualSession,
+					}
+					c.Set(sessionContext, &session)
+				}
+				nextError = next(c)
+			}), options)
+			handler(c.Response(), c.Request())
+			return nextError
+		}
+	}
+}
+
+// RequireClaims checks the JWT payload of the session set by Middleware. The next handler is not called if a claim
+// is invalid, which is answered with a 403 by default, or if Middleware was not used before it, which is answered with a 500
+func RequireClaims(validators ...claims.Validator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var actualSession *supertokens.Session
+			if session := GetSessionFromRequest(c); session != nil {
+				actualSession = session.actualSession
+			}
+			if err := supertokens.CheckRequiredClaims(actualSession, validators...); err != nil {
+				return HandleErrorAndRespond(err, c)
+			}
+			return next(c)
+		}
+	}
+}
+
+// HandleErrorAndRespond if error handlers are provided, then uses those, else does default error handling depending on the type of error.
+// It returns nil since the error has been responded to, so that handlers can return it
+func HandleErrorAndRespond(err error, c echo.Context) error {
+	supertokens.HandleErrorAndRespond(err, c.Response())
+	return nil
+}
@@ -0,0 +1,110 @@
+package supertokens
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/supertokenstest"
+)
+
+// newTestServer configures the package with a fake core and returns an echo server with routes like the example app
+func newTestServer(t *testing.T) (*echo.Echo, *supertokenstest.Core) {
+	fakeCore := supertokenstest.NewCore(supertokenstest.Options{})
+	core.ResetQuerier()
+	core.ResetHandshakeInfo()
+	core.ResetError()
+	Config(ConfigMap{Hosts: fakeCore.URL()})
+
+	e := echo.New()
+	e.POST("/login", func(c echo.Context) error {
+		if _, err := CreateNewSession(c, "user", map[string]interface{}{"role": "user"}); err != nil {
+			return HandleErrorAndRespond(err, c)
+		}
+		return c.String(200, "user")
+	})
+	e.GET("/", func(c echo.Context) error {
+		return c.String(200, GetSessionFromRequest(c).GetUserID())
+	}, Middleware())
+	e.POST("/refresh", func(c echo.Context) error {
+		return c.String(200, "refresh success")
+	}, Middleware())
+	e.GET("/admin", func(c echo.Context) error {
+		t.Error("handler should not have been called")
+		return nil
+	}, Middleware(), RequireClaims(claims.HasRole("admin")))
+	e.GET("/unprotected", func(c echo.Context) error {
+		t.Error("handler should not have been called")
+		return nil
+	}, RequireClaims(claims.HasRole("admin")))
+	sessionRequired := false
+	e.GET("/home", func(c echo.Context) error {
+		if session := GetSessionFromRequest(c); session != nil {
+			return c.String(200, session.GetUserID())
+		}
+		return c.String(200, "anonymous")
+	}, MiddlewareWithOptions(supertokens.MiddlewareOptions{SessionRequired: &sessionRequired}))
+	return e, fakeCore
+}
+
+func serve(e *echo.Echo, method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func Test_Middleware(t *testing.T) {
+	e, fakeCore := newTestServer(t)
+	defer fakeCore.Close()
+
+	recorder := serve(e, "POST", "/login", nil)
+	assert.Equal(t, 200, recorder.Code)
+	cookies := recorder.Result().Cookies()
+
+	recorder = serve(e, "GET", "/", cookies)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+
+	recorder = serve(e, "POST", "/refresh", cookies)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "refresh success", recorder.Body.String())
+	assert.Equal(t, 1, fakeCore.RequestCount("/session/refresh"))
+
+	recorder = serve(e, "GET", "/", nil)
+	assert.Equal(t, 401, recorder.Code)
+}
+
+func Test_RequireClaims(t *testing.T) {
+	e, fakeCore := newTestServer(t)
+	defer fakeCore.Close()
+
+	cookies := serve(e, "POST", "/login", nil).Result().Cookies()
+	recorder := serve(e, "GET", "/admin", cookies)
+	assert.Equal(t, 403, recorder.Code)
+
+	recorder = serve(e, "GET", "/unprotected", cookies)
+	assert.Equal(t, 500, recorder.Code, "RequireClaims without Middleware is a server error")
+}
+
+func Test_Middleware_OptionalSession(t *testing.T) {
+	e, fakeCore := newTestServer(t)
+	defer fakeCore.Close()
+
+	recorder := serve(e, "GET", "/home", nil)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "anonymous", recorder.Body.String())
+
+	cookies := serve(e, "POST", "/login", nil).Result().Cookies()
+	recorder = serve(e, "GET", "/home", cookies)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+}
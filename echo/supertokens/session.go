@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
+)
+
+// Session object returned for managing a session
+type Session struct {
+	actualSession *supertokens.Session
+}
+
+// RevokeSession function used to revoke a session for this session
+func (session *Session) RevokeSession() error {
+	return session.actualSession.RevokeSession()
+}
+
+// GetSessionData function used to get session data for this session
+func (session *Session) GetSessionData() (map[string]interface{}, error) {
+	return session.actualSession.GetSessionData()
+}
+
+// UpdateSessionData function used to update session data for this session
+func (session *Session) UpdateSessionData(newSessionData map[string]interface{}) error {
+	return session.actualSession.UpdateSessionData(newSessionData)
+}
+
+// GetUserID function gets the user for this session
+func (session *Session) GetUserID() string {
+	return session.actualSession.GetUserID()
+}
+
+// GetJWTPayload function gets the jwt payload for this session
+func (session *Session) GetJWTPayload() map[string]interface{} {
+	return session.actualSession.GetJWTPayload()
+}
+
+// GetHandle function gets the session handle for this session
+func (session *Session) GetHandle() string {
+	return session.actualSession.GetHandle()
+}
+
+// GetAccessToken function gets the access token for this session
+func (session *Session) GetAccessToken() string {
+	return session.actualSession.GetAccessToken()
+}
+
+// GetAccessTokenExpiry function gets the time at which the current access token of this session expires.
+// It returns an error if the access token cannot be read
+func (session *Session) GetAccessTokenExpiry() (time.Time, error) {
+	return session.actualSession.GetAccessTokenExpiry()
+}
+
+// GetAccessTokenTimeCreated function gets the time at which the current access token of this session was created.
+// Every refresh issues a new access token, so this is not when the session was created
+func (session *Session) GetAccessTokenTimeCreated() (time.Time, error) {
+	return session.actualSession.GetAccessTokenTimeCreated()
+}
+
+// GetAntiCsrfToken function gets the anti-csrf token of this session. It is empty if anti-csrf is disabled in the core
+func (session *Session) GetAntiCsrfToken() (string, error) {
+	return session.actualSession.GetAntiCsrfToken()
+}
+
+// IsFreshlyRefreshed function tells if the access token of this session was issued by a refresh
+// that has not yet been confirmed by the core
+func (session *Session) IsFreshlyRefreshed() (bool, error) {
+	return session.actualSession.IsFreshlyRefreshed()
+}
+
+// UpdateJWTPayload function used to update jwt payload for this session
+func (session *Session) UpdateJWTPayload(newJWTPayload map[string]interface{}) error {
+	return session.actualSession.UpdateJWTPayload(newJWTPayload)
+}
+
+// DecodeJWTPayload fills target, which must be a pointer, with the jwt payload for this session
+func (session *Session) DecodeJWTPayload(target interface{}) error {
+	return session.actualSession.DecodeJWTPayload(target)
+}
+
+// DecodeSessionData fills target, which must be a pointer, with the session data for this session
+func (session *Session) DecodeSessionData(target interface{}) error {
+	return session.actualSession.DecodeSessionData(target)
+}
+
+// UpdateJWTPayloadFrom function used to update jwt payload for this session with a typed value
+func (session *Session) UpdateJWTPayloadFrom(newJWTPayload interface{}) error {
+	return session.actualSession.UpdateJWTPayloadFrom(newJWTPayload)
+}
+
+// UpdateSessionDataFrom function used to update session data for this session with a typed value
+func (session *Session) UpdateSessionDataFrom(newSessionData interface{}) error {
+	return session.actualSession.UpdateSessionDataFrom(newSessionData)
+}
+
+// ValidateClaims function used to check the jwt payload of this session. It returns an errors.InvalidClaimError
+// if a claim is invalid
+func (session *Session) ValidateClaims(validators ...claims.Validator) error {
+	return session.actualSession.ValidateClaims(validators...)
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
+)
+
+// SessionContext string to get session struct from context if using Echo
+const sessionContext string = "supertokens_session_key"
+
+// ConfigMap add key value params for session behaviour
+type ConfigMap struct {
+	Hosts           string
+	AccessTokenPath string
+	RefreshAPIPath  string
+	CookieDomain    string
+	CookieSecure    *bool
+	CookieSameSite  string
+	APIKey          string
+	// HTTPClient used to query the cores. Set its Transport to control connection pooling
+	HTTPClient *http.Client
+	// RequestTimeout for a single attempt of a query to a core. Zero means no timeout
+	RequestTimeout time.Duration
+	// RetryPolicy for queries that time out, fail with a 5xx response or cannot reach a core
+	RetryPolicy *supertokens.RetryPolicy
+	// HealthCheck configures when cores are taken out of rotation and probed until they recover
+	HealthCheck *supertokens.HealthCheckOptions
+	// SigningKey configures background refreshing of the JWT signing key and how long rotated out keys are accepted
+	SigningKey *supertokens.SigningKeyOptions
+	// JWTSigningPublicKey pins the key used to verify access tokens. GetSession then never queries the core
+	JWTSigningPublicKey string
+	// JWTSigningPublicKeyPath is a file with the key used to verify access tokens, read again when it changes.
+	// GetSession then never queries the core
+	JWTSigningPublicKeyPath string
+	// TokenTransferMethod is one of cookie (the default), header or any. See supertokens.TokenTransferMethodCookie
+	TokenTransferMethod string
+	// Logger receives debug events about session verification, queries to the cores and cleared cookies.
+	// A *slog.Logger can be used. By default nothing is logged, unless the DEBUG environment variable
+	// contains com.supertokens
+	Logger supertokens.Logger
+	// Metrics receives counters of session events and verifications, and the duration of queries to the cores
+	Metrics supertokens.Metrics
+	// Tracer traces GetSession and every query to the cores, and sends the trace context to the cores
+	Tracer supertokens.Tracer
+	// ClaimValidators check the JWT payload of every session verified by Middleware. Use RequireClaims
+	// for claims that only some handlers need
+	ClaimValidators []claims.Validator
+}
+
+// Config used to set locations of SuperTokens instances
+func Config(config ConfigMap) {
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:           config.Hosts,
+		AccessTokenPath: config.AccessTokenPath,
+		RefreshAPIPath:  config.RefreshAPIPath,
+		CookieDomain:    config.CookieDomain,
+		CookieSecure:    config.CookieSecure,
+		CookieSameSite:  config.CookieSameSite,
+		APIKey:          config.APIKey,
+		HTTPClient:      config.HTTPClient,
+		RequestTimeout:  config.RequestTimeout,
+		RetryPolicy:     config.RetryPolicy,
+		HealthCheck:     config.HealthCheck,
+		SigningKey:      config.SigningKey,
+		Logger:          config.Logger,
+		Metrics:         config.Metrics,
+		Tracer:          config.Tracer,
+		ClaimValidators: config.ClaimValidators,
+
+		JWTSigningPublicKey:     config.JWTSigningPublicKey,
+		JWTSigningPublicKeyPath: config.JWTSigningPublicKeyPath,
+		TokenTransferMethod:     config.TokenTransferMethod,
+	})
+}
+
+// CreateNewSession function used to create a new SuperTokens session
+func CreateNewSession(c echo.Context, userID string,
+	payload ...map[string]interface{}) (Session, error) {
+	actualSession, err := supertokens.CreateNewSession(c.Response(), userID, payload...)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// CreateNewSessionWithOptions function used to create a new SuperTokens session with a typed JWT payload and session data
+func CreateNewSessionWithOptions(c echo.Context, userID string, options supertokens.SessionOptions) (Session, error) {
+	actualSession, err := supertokens.CreateNewSessionWithOptions(c.Response(), userID, options)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// NewSessionResult is a new session along with the details of the tokens that were issued
+type NewSessionResult struct {
+	Session              Session
+	AccessTokenExpiry    time.Time
+	RefreshTokenExpiry   time.Time
+	IDRefreshTokenExpiry time.Time
+	// AntiCsrfToken is empty if anti-csrf is disabled in the core
+	AntiCsrfToken string
+}
+
+// NewSession function used to create a new SuperTokens session. See supertokens.WithJWTPayload for the options
+func NewSession(c echo.Context, userID string, options ...supertokens.NewSessionOption) (NewSessionResult, error) {
+	options = append([]supertokens.NewSessionOption{supertokens.WithRequest(c.Request())}, options...)
+	result, err := supertokens.NewSessionWithContext(c.Request().Context(), c.Response(), userID, options...)
+	if err != nil {
+		return NewSessionResult{}, err
+	}
+	return NewSessionResult{
+		Session: Session{
+			actualSession: &result.Session,
+		},
+		AccessTokenExpiry:    result.AccessTokenExpiry,
+		RefreshTokenExpiry:   result.RefreshTokenExpiry,
+		IDRefreshTokenExpiry: result.IDRefreshTokenExpiry,
+		AntiCsrfToken:        result.AntiCsrfToken,
+	}, nil
+}
+
+// GetSession function used to verify a session
+func GetSession(c echo.Context, doAntiCsrfCheck bool) (Session, error) {
+	actualSession, err := supertokens.GetSession(c.Response(), c.Request(), doAntiCsrfCheck)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// GetOptionalSession function used to verify a session if the request has one. It returns nil, and no error,
+// if the request has no session tokens
+func GetOptionalSession(c echo.Context, doAntiCsrfCheck bool) (*Session, error) {
+	actualSession, err := supertokens.GetOptionalSession(c.Response(), c.Request(), doAntiCsrfCheck)
+	if err != nil || actualSession == nil {
+		return nil, err
+	}
+	return &Session{
+		actualSession: actualSession,
+	}, nil
+}
+
+// RefreshSession function used to refresh a session
+func RefreshSession(c echo.Context) (Session, error) {
+	actualSession, err := supertokens.RefreshSession(c.Response(), c.Request())
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// RevokeAllSessionsForUser function used to revoke all sessions for a user
+func RevokeAllSessionsForUser(userID string) ([]string, error) {
+	return supertokens.RevokeAllSessionsForUser(userID)
+}
+
+// GetAllSessionHandlesForUser function used to get all sessions for a user
+func GetAllSessionHandlesForUser(userID string) ([]string, error) {
+	return supertokens.GetAllSessionHandlesForUser(userID)
+}
+
+// RevokeSession function used to revoke a specific session
+func RevokeSession(sessionHandle string) (bool, error) {
+	return supertokens.RevokeSession(sessionHandle)
+}
+
+// RevokeMultipleSessions function used to revoke a list of sessions
+func RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
+	return supertokens.RevokeMultipleSessions(sessionHandles)
+}
+
+// GetSessionData function used to get session data for the given handle
+func GetSessionData(sessionHandle string) (map[string]interface{}, error) {
+	return supertokens.GetSessionData(sessionHandle)
+}
+
+// UpdateSessionData function used to update session data for the given handle
+func UpdateSessionData(sessionHandle string, newSessionData map[string]interface{}) error {
+	return supertokens.UpdateSessionData(sessionHandle, newSessionData)
+}
+
+// SetRelevantHeadersForOptionsAPI function is used to set headers specific to SuperTokens for OPTIONS API
+func SetRelevantHeadersForOptionsAPI(c echo.Context) {
+	supertokens.SetRelevantHeadersForOptionsAPI(c.Response())
+}
+
+// GetCORSAllowedHeaders function is used to get header keys that are used by SuperTokens
+func GetCORSAllowedHeaders() []string {
+	return supertokens.GetCORSAllowedHeaders()
+}
+
+// GetCORSExposedHeaders function is used to get the keys of the response headers the frontend reads
+func GetCORSExposedHeaders() []string {
+	return supertokens.GetCORSExposedHeaders()
+}
+
+// GetJWTPayload function used to get jwt payload for the given handle
+func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
+	return supertokens.GetJWTPayload(sessionHandle)
+}
+
+// GetSessionInformation function used to get the session data and jwt payload for the given handle
+func GetSessionInformation(sessionHandle string) (supertokens.SessionInformation, error) {
+	return supertokens.GetSessionInformation(sessionHandle)
+}
+
+// UpdateJWTPayload function used to update jwt payload for the given handle
+func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}) error {
+	return supertokens.UpdateJWTPayload(sessionHandle, newJWTPayload)
+}
+
+// OnTokenTheftDetected function to override default behaviour of handling token thefts
+func OnTokenTheftDetected(handler func(string, string, http.ResponseWriter)) {
+	supertokens.OnTokenTheftDetected(handler)
+}
+
+// OnUnauthorized function to override default behaviour of handling Unauthorized error
+func OnUnauthorized(handler func(error, http.ResponseWriter)) {
+	supertokens.OnUnauthorized(handler)
+}
+
+// OnTryRefreshToken function to override default behaviour of handling try refresh token errors
+func OnTryRefreshToken(handler func(error, http.ResponseWriter)) {
+	supertokens.OnTryRefreshToken(handler)
+}
+
+// OnGeneralError function to override default behaviour of handling general errors
+func OnGeneralError(handler func(error, http.ResponseWriter)) {
+	supertokens.OnGeneralError(handler)
+}
+
+// OnOfflineVerificationError function to override default behaviour of handling access tokens that cannot be verified with the pinned key
+func OnOfflineVerificationError(handler func(error, http.ResponseWriter)) {
+	supertokens.OnOfflineVerificationError(handler)
+}
+
+// OnInvalidClaim function to override default behaviour of handling sessions whose jwt payload fails claim validation
+func OnInvalidClaim(handler func(error, http.ResponseWriter)) {
+	supertokens.OnInvalidClaim(handler)
+}
+
+// OnSessionEvent function to be notified of session events of the given types, or of all of them if no type is given
+func OnSessionEvent(handler func(supertokens.SessionEvent), types ...supertokens.SessionEventType) {
+	supertokens.OnSessionEvent(handler, types...)
+}
+
+// OnSigningKeyRotated function to be notified whenever the JWT signing key changes
+func OnSigningKeyRotated(handler func(supertokens.SigningKeyRotation)) {
+	supertokens.OnSigningKeyRotated(handler)
+}
+
+// GetCoreHostsStatus function used to get the health of every configured core
+func GetCoreHostsStatus() []supertokens.CoreHostStatus {
+	return supertokens.GetCoreHostsStatus()
+}
+
+// GetSessionFromRequest returns the verified session object if present, otherwise returns nil
+func GetSessionFromRequest(c echo.Context) *Session {
+	if session, ok := c.Get(sessionContext).(*Session); ok {
+		return session
+	}
+	return nil
+}
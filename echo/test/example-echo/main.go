@@ -0,0 +1,348 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/supertokens/supertokens-go/echo/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+)
+
+var noOfTimesGetSessionCalledDuringTest int = 0
+var noOfTimesRefreshCalledDuringTest int = 0
+
+func main() {
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:          "http://localhost:9000",
+		CookieSameSite: "lax",
+	})
+	r := echo.New()
+
+	// it's important to set CORS before any route. Otherwise it will not work
+	r.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     []string{"http://localhost.org:8080"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "HEAD", "OPTIONS"},
+		AllowHeaders:     append([]string{"Content-Type"}, supertokens.GetCORSAllowedHeaders()...),
+		AllowCredentials: true,
+	}))
+	r.Any("/login", login)
+	r.Any("/testUserConfig", testUserConfig)
+	r.Any("/multipleInterceptors", multipleInterceptors)
+	r.Any("/", defaultHandler, supertokens.Middleware())
+	r.Any("/beforeeach", beforeeach)
+	r.Any("/testing", testing)
+	r.Any("/logout", logout, supertokens.Middleware())
+	r.Any("/revokeAll", revokeAll, supertokens.Middleware())
+	r.Any("/refresh", refresh, supertokens.Middleware())
+	r.Any("/refreshCalledTime", refreshCalledTime)
+	r.Any("/getSessionCalledTime", getSessionCalledTime)
+	r.Any("/ping", ping)
+	r.Any("/testHeader", testHeader)
+	r.Any("/checkDeviceInfo", checkDeviceInfo)
+	r.Any("/checkAllowCredentials", checkAllowCredentials)
+	r.Any("/testError", testError)
+	r.Any("/index.html", index)
+	r.Any("/fail", fail)
+	r.Any("/update-jwt", updateJwt, supertokens.Middleware())
+	supertokens.OnTryRefreshToken(customOnTryRefreshTokenError)
+	supertokens.OnUnauthorized(customOnUnauthorizedError)
+	supertokens.OnGeneralError(customOnGeneralError)
+	port := "8080"
+	if len(os.Args) == 2 {
+		port = os.Args[1]
+	}
+	r.Logger.Fatal(r.Start("0.0.0.0:" + port))
+}
+
+func fail(c echo.Context) error {
+	w := c.Response()
+	w.WriteHeader(404)
+	w.Write([]byte(""))
+	return nil
+}
+
+func index(c echo.Context) error {
+	w := c.Response()
+	dat, _ := ioutil.ReadFile("./static/index.html")
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(dat)
+	return nil
+}
+
+func login(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+
+	var body map[string]interface{}
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		response.Write([]byte("error when parsing body"))
+		return nil
+	}
+	userID := body["userId"].(string)
+	_, err = supertokens.CreateNewSession(c, userID)
+
+	if err != nil {
+		return supertokens.HandleErrorAndRespond(err, c)
+	}
+	response.Write([]byte(userID))
+	return nil
+}
+
+func testUserConfig(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+	response.Write([]byte(""))
+	return nil
+}
+func multipleInterceptors(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+	interceptorheader2 := request.Header.Get("interceptorheader2")
+	interceptorheader1 := request.Header.Get("interceptorheader1")
+
+	var resp string
+	if interceptorheader2 != "" && interceptorheader1 != "" {
+		resp = "success"
+	} else {
+		resp = "failure"
+	}
+	response.Write([]byte(resp))
+	return nil
+}
+
+func defaultHandler(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "GET" {
+		response.Write([]byte("incorrect Method, requires GET"))
+		return nil
+	}
+	noOfTimesGetSessionCalledDuringTest++
+	var session *supertokens.Session = supertokens.GetSessionFromRequest(c)
+	response.Write([]byte(session.GetUserID()))
+	return nil
+}
+
+func updateJwt(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method == "GET" {
+		session := supertokens.GetSessionFromRequest(c)
+		json.NewEncoder(response).Encode(session.GetJWTPayload())
+	} else if request.Method == "POST" {
+		var body map[string]interface{}
+		err := json.NewDecoder(request.Body).Decode(&body)
+		if err != nil {
+			response.Write([]byte("error when parsing the body"))
+			return nil
+		}
+		session := supertokens.GetSessionFromRequest(c)
+		session.UpdateJWTPayload(body)
+		json.NewEncoder(response).Encode(session.GetJWTPayload())
+	} else {
+		response.Write([]byte("incorrect Method, requires POST or GET"))
+	}
+	return nil
+}
+
+func beforeeach(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+	noOfTimesRefreshCalledDuringTest = 0
+	noOfTimesGetSessionCalledDuringTest = 0
+	core.ResetHandshakeInfo()
+	response.Write([]byte(""))
+	return nil
+}
+
+func testing(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	value := request.Header.Get("testing")
+	if value != "" {
+		response.Header().Set("testing", value)
+	}
+	response.Write([]byte("success"))
+	return nil
+}
+
+func logout(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+
+	session := supertokens.GetSessionFromRequest(c)
+	err := session.RevokeSession()
+	if err != nil {
+		return supertokens.HandleErrorAndRespond(err, c)
+	}
+	response.Write([]byte("success"))
+	return nil
+}
+
+func revokeAll(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+	session := supertokens.GetSessionFromRequest(c)
+	userID := session.GetUserID()
+	supertokens.RevokeAllSessionsForUser(userID)
+	response.Write([]byte("success"))
+	return nil
+}
+
+func refresh(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+	noOfTimesRefreshCalledDuringTest++
+	response.Write([]byte("refresh success"))
+	return nil
+}
+
+func refreshCalledTime(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "GET" {
+		response.Write([]byte("incorrect Method, requires GET"))
+		return nil
+	}
+	response.Write([]byte(strconv.Itoa(noOfTimesRefreshCalledDuringTest)))
+	return nil
+}
+
+func getSessionCalledTime(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "GET" {
+		response.Write([]byte("incorrect Method, requires GET"))
+		return nil
+	}
+	response.Write([]byte(strconv.Itoa(noOfTimesGetSessionCalledDuringTest)))
+	return nil
+}
+
+func ping(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "GET" {
+		response.Write([]byte("incorrect Method, requires GET"))
+		return nil
+	}
+	response.Write([]byte(""))
+	return nil
+}
+
+func testHeader(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "GET" {
+		response.Write([]byte("incorrect Method, requires GET"))
+		return nil
+	}
+	testheader := request.Header.Get("st-custom-header")
+	success := testheader != ""
+	json.NewEncoder(response).Encode(map[string]interface{}{
+		"success": success,
+	})
+	return nil
+}
+
+func checkDeviceInfo(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "GET" {
+		response.Write([]byte("incorrect Method, requires GET"))
+		return nil
+	}
+	sdkName := request.Header.Get("supertokens-sdk-name")
+	sdkVersion := request.Header.Get("supertokens-sdk-version")
+	response.Write([]byte(strconv.FormatBool(sdkName == "website" && sdkVersion != "")))
+	return nil
+}
+
+func checkAllowCredentials(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "POST" {
+		response.Write([]byte("incorrect Method, requires POST"))
+		return nil
+	}
+	response.Write([]byte(strconv.FormatBool(request.Header.Get("allow-credentials") != "")))
+	return nil
+}
+
+func testError(c echo.Context) error {
+	response := c.Response()
+	request := c.Request()
+	if request.Method != "GET" {
+		response.Write([]byte("incorrect Method, requires GET"))
+		return nil
+	}
+	response.WriteHeader(http.StatusInternalServerError)
+	response.Write([]byte("test error message"))
+	return nil
+}
+
+func customOnTryRefreshTokenError(err error, response http.ResponseWriter) {
+	response.WriteHeader(401)
+	response.Write([]byte(""))
+
+}
+
+func customOnUnauthorizedError(err error, response http.ResponseWriter) {
+	response.WriteHeader(401)
+	response.Write([]byte(""))
+}
+
+func customOnGeneralError(err error, response http.ResponseWriter) {
+	response.WriteHeader(http.StatusInternalServerError)
+	response.Write([]byte("Something went wrong"))
+}
@@ -5,6 +5,6 @@ go 1.13
 require (
 	github.com/gin-contrib/cors v1.3.1
 	github.com/gin-gonic/gin v1.6.3
-	github.com/gorilla/handlers v1.4.2 // indirect
-	github.com/supertokens/supertokens-go v1.4.2-0.20201018174106-272de5dbd1d9
+	github.com/stretchr/testify v1.6.1
+	github.com/supertokens/supertokens-go v1.5.0
 )
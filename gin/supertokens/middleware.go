@@ -21,22 +21,31 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
 )
 
 // Middleware for verifying and refreshing session.
 func Middleware(condition ...bool) func(*gin.Context) {
+	options := supertokens.MiddlewareOptions{}
+	if len(condition) == 1 {
+		options.AntiCsrf = &condition[0]
+	}
+	return MiddlewareWithOptions(options)
+}
+
+// MiddlewareWithOptions for verifying and refreshing session. The context is aborted if there is an error
+func MiddlewareWithOptions(options supertokens.MiddlewareOptions) func(*gin.Context) {
+	handleError := options.ErrorHandler
+	if handleError == nil {
+		handleError = supertokens.HandleErrorAndRespond
+	}
 	return func(c *gin.Context) {
-		var params = []interface{}{}
-		if len(condition) == 1 {
-			params = append(params, condition[0])
-		} else {
-			params = append(params, nil)
-		}
-		params = append(params, func(err error, w http.ResponseWriter) {
+		requestOptions := options
+		requestOptions.ErrorHandler = func(err error, w http.ResponseWriter) {
 			c.Abort()
-			supertokens.HandleErrorAndRespond(err, w)
-		})
-		handler := supertokens.Middleware(func(w http.ResponseWriter, r *http.Request) {
+			handleError(err, w)
+		}
+		handler := supertokens.MiddlewareWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			actualSession := supertokens.GetSessionFromRequest(r)
 			if actualSession != nil {
 				session := Session{
@@ -45,11 +54,28 @@ func Middleware(condition ...bool) func(*gin.Context) {
 				c.Set(sessionContext, &session)
 			}
 			c.Next()
-		}, params...)
+		}), requestOptions)
 		handler(c.Writer, c.Request)
 	}
 }
 
+// RequireClaims checks the JWT payload of the session set by Middleware. The context is aborted if a claim is invalid,
+// which is answered with a 403 by default, or if Middleware was not used before it, which is answered with a 500
+func RequireClaims(validators ...claims.Validator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		var actualSession *supertokens.Session
+		if session := GetSessionFromRequest(c); session != nil {
+			actualSession = session.actualSession
+		}
+		if err := supertokens.CheckRequiredClaims(actualSession, validators...); err != nil {
+			c.Abort()
+			supertokens.HandleErrorAndRespond(err, c.Writer)
+			return
+		}
+		c.Next()
+	}
+}
+
 // HandleErrorAndRespond if error handlers are provided, then uses those, else does default error handling depending on the type of error
 func HandleErrorAndRespond(err error, c *gin.Context) {
 	supertokens.HandleErrorAndRespond(err, c.Writer)
@@ -0,0 +1,119 @@
+package supertokens
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/supertokenstest"
+)
+
+// newTestRouter configures the package with a fake core and returns a gin router with routes like the example app
+func newTestRouter(t *testing.T) (*gin.Engine, *supertokenstest.Core) {
+	gin.SetMode(gin.TestMode)
+	fakeCore := supertokenstest.NewCore(supertokenstest.Options{})
+	core.ResetQuerier()
+	core.ResetHandshakeInfo()
+	core.ResetError()
+	Config(ConfigMap{Hosts: fakeCore.URL()})
+
+	router := gin.New()
+	router.POST("/login", func(c *gin.Context) {
+		if _, err := CreateNewSession(c, "user", map[string]interface{}{"role": "user"}); err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.String(200, "user")
+	})
+	router.GET("/", Middleware(), func(c *gin.Context) {
+		c.String(200, GetSessionFromRequest(c).GetUserID())
+	})
+	router.POST("/refresh", Middleware(), func(c *gin.Context) {
+		c.String(200, "refresh success")
+	})
+	router.GET("/admin", Middleware(), RequireClaims(claims.HasRole("admin")), func(c *gin.Context) {
+		t.Error("handler should not have been called")
+	})
+	router.GET("/user", Middleware(), RequireClaims(claims.HasRole("user")), func(c *gin.Context) {
+		c.String(200, GetSessionFromRequest(c).GetUserID())
+	})
+	router.GET("/unprotected", RequireClaims(claims.HasRole("user")), func(c *gin.Context) {
+		t.Error("handler should not have been called")
+	})
+	sessionRequired := false
+	router.GET("/home", MiddlewareWithOptions(supertokens.MiddlewareOptions{SessionRequired: &sessionRequired}),
+		func(c *gin.Context) {
+			if session := GetSessionFromRequest(c); session != nil {
+				c.String(200, session.GetUserID())
+				return
+			}
+			c.String(200, "anonymous")
+		})
+	return router, fakeCore
+}
+
+func serve(router *gin.Engine, method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func Test_Middleware(t *testing.T) {
+	router, fakeCore := newTestRouter(t)
+	defer fakeCore.Close()
+
+	recorder := serve(router, "POST", "/login", nil)
+	assert.Equal(t, 200, recorder.Code)
+	cookies := recorder.Result().Cookies()
+
+	recorder = serve(router, "GET", "/", cookies)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+
+	recorder = serve(router, "POST", "/refresh", cookies)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "refresh success", recorder.Body.String())
+	assert.Equal(t, 1, fakeCore.RequestCount("/session/refresh"))
+
+	recorder = serve(router, "GET", "/", nil)
+	assert.Equal(t, 401, recorder.Code)
+}
+
+func Test_RequireClaims(t *testing.T) {
+	router, fakeCore := newTestRouter(t)
+	defer fakeCore.Close()
+
+	cookies := serve(router, "POST", "/login", nil).Result().Cookies()
+	recorder := serve(router, "GET", "/admin", cookies)
+	assert.Equal(t, 403, recorder.Code)
+
+	recorder = serve(router, "GET", "/user", cookies)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+
+	recorder = serve(router, "GET", "/unprotected", cookies)
+	assert.Equal(t, 500, recorder.Code, "RequireClaims without Middleware is a server error")
+}
+
+func Test_Middleware_OptionalSession(t *testing.T) {
+	router, fakeCore := newTestRouter(t)
+	defer fakeCore.Close()
+
+	recorder := serve(router, "GET", "/home", nil)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "anonymous", recorder.Body.String())
+
+	cookies := serve(router, "POST", "/login", nil).Result().Cookies()
+	recorder = serve(router, "GET", "/home", cookies)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+}
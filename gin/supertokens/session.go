@@ -17,7 +17,10 @@
 package supertokens
 
 import (
+	"time"
+
 	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
 )
 
 // Session object returned for managing a session
@@ -60,7 +63,56 @@ func (session *Session) GetAccessToken() string {
 	return session.actualSession.GetAccessToken()
 }
 
+// GetAccessTokenExpiry function gets the time at which the current access token of this session expires.
+// It returns an error if the access token cannot be read
+func (session *Session) GetAccessTokenExpiry() (time.Time, error) {
+	return session.actualSession.GetAccessTokenExpiry()
+}
+
+// GetAccessTokenTimeCreated function gets the time at which the current access token of this session was created.
+// Every refresh issues a new access token, so this is not when the session was created
+func (session *Session) GetAccessTokenTimeCreated() (time.Time, error) {
+	return session.actualSession.GetAccessTokenTimeCreated()
+}
+
+// GetAntiCsrfToken function gets the anti-csrf token of this session. It is empty if anti-csrf is disabled in the core
+func (session *Session) GetAntiCsrfToken() (string, error) {
+	return session.actualSession.GetAntiCsrfToken()
+}
+
+// IsFreshlyRefreshed function tells if the access token of this session was issued by a refresh
+// that has not yet been confirmed by the core
+func (session *Session) IsFreshlyRefreshed() (bool, error) {
+	return session.actualSession.IsFreshlyRefreshed()
+}
+
 // UpdateJWTPayload function used to update jwt payload for this session
 func (session *Session) UpdateJWTPayload(newJWTPayload map[string]interface{}) error {
 	return session.actualSession.UpdateJWTPayload(newJWTPayload)
 }
+
+// DecodeJWTPayload fills target, which must be a pointer, with the jwt payload for this session
+func (session *Session) DecodeJWTPayload(target interface{}) error {
+	return session.actualSession.DecodeJWTPayload(target)
+}
+
+// DecodeSessionData fills target, which must be a pointer, with the session data for this session
+func (session *Session) DecodeSessionData(target interface{}) error {
+	return session.actualSession.DecodeSessionData(target)
+}
+
+// UpdateJWTPayloadFrom function used to update jwt payload for this session with a typed value
+func (session *Session) UpdateJWTPayloadFrom(newJWTPayload interface{}) error {
+	return session.actualSession.UpdateJWTPayloadFrom(newJWTPayload)
+}
+
+// UpdateSessionDataFrom function used to update session data for this session with a typed value
+func (session *Session) UpdateSessionDataFrom(newSessionData interface{}) error {
+	return session.actualSession.UpdateSessionDataFrom(newSessionData)
+}
+
+// ValidateClaims function used to check the jwt payload of this session. It returns an errors.InvalidClaimError
+// if a claim is invalid
+func (session *Session) ValidateClaims(validators ...claims.Validator) error {
+	return session.actualSession.ValidateClaims(validators...)
+}
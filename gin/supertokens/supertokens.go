@@ -18,9 +18,11 @@ package supertokens
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
 )
 
 // SessionContext string to get session struct from context if using Gin
@@ -35,6 +37,34 @@ type ConfigMap struct {
 	CookieSecure    *bool
 	CookieSameSite  string
 	APIKey          string
+	// HTTPClient used to query the cores. Set its Transport to control connection pooling
+	HTTPClient *http.Client
+	// RequestTimeout for a single attempt of a query to a core. Zero means no timeout
+	RequestTimeout time.Duration
+	// RetryPolicy for queries that time out, fail with a 5xx response or cannot reach a core
+	RetryPolicy *supertokens.RetryPolicy
+	// HealthCheck configures when cores are taken out of rotation and probed until they recover
+	HealthCheck *supertokens.HealthCheckOptions
+	// SigningKey configures background refreshing of the JWT signing key and how long rotated out keys are accepted
+	SigningKey *supertokens.SigningKeyOptions
+	// JWTSigningPublicKey pins the key used to verify access tokens. GetSession then never queries the core
+	JWTSigningPublicKey string
+	// JWTSigningPublicKeyPath is a file with the key used to verify access tokens, read again when it changes.
+	// GetSession then never queries the core
+	JWTSigningPublicKeyPath string
+	// TokenTransferMethod is one of cookie (the default), header or any. See supertokens.TokenTransferMethodCookie
+	TokenTransferMethod string
+	// Logger receives debug events about session verification, queries to the cores and cleared cookies.
+	// A *slog.Logger can be used. By default nothing is logged, unless the DEBUG environment variable
+	// contains com.supertokens
+	Logger supertokens.Logger
+	// Metrics receives counters of session events and verifications, and the duration of queries to the cores
+	Metrics supertokens.Metrics
+	// Tracer traces GetSession and every query to the cores, and sends the trace context to the cores
+	Tracer supertokens.Tracer
+	// ClaimValidators check the JWT payload of every session verified by Middleware. Use RequireClaims
+	// for claims that only some handlers need
+	ClaimValidators []claims.Validator
 }
 
 // Config used to set locations of SuperTokens instances
@@ -47,6 +77,19 @@ func Config(config ConfigMap) {
 		CookieSecure:    config.CookieSecure,
 		CookieSameSite:  config.CookieSameSite,
 		APIKey:          config.APIKey,
+		HTTPClient:      config.HTTPClient,
+		RequestTimeout:  config.RequestTimeout,
+		RetryPolicy:     config.RetryPolicy,
+		HealthCheck:     config.HealthCheck,
+		SigningKey:      config.SigningKey,
+		Logger:          config.Logger,
+		Metrics:         config.Metrics,
+		Tracer:          config.Tracer,
+		ClaimValidators: config.ClaimValidators,
+
+		JWTSigningPublicKey:     config.JWTSigningPublicKey,
+		JWTSigningPublicKeyPath: config.JWTSigningPublicKeyPath,
+		TokenTransferMethod:     config.TokenTransferMethod,
 	})
 }
 
@@ -62,6 +105,45 @@ func CreateNewSession(c *gin.Context, userID string,
 	}, nil
 }
 
+// CreateNewSessionWithOptions function used to create a new SuperTokens session with a typed JWT payload and session data
+func CreateNewSessionWithOptions(c *gin.Context, userID string, options supertokens.SessionOptions) (Session, error) {
+	actualSession, err := supertokens.CreateNewSessionWithOptions(c.Writer, userID, options)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// NewSessionResult is a new session along with the details of the tokens that were issued
+type NewSessionResult struct {
+	Session              Session
+	AccessTokenExpiry    time.Time
+	RefreshTokenExpiry   time.Time
+	IDRefreshTokenExpiry time.Time
+	// AntiCsrfToken is empty if anti-csrf is disabled in the core
+	AntiCsrfToken string
+}
+
+// NewSession function used to create a new SuperTokens session. See supertokens.WithJWTPayload for the options
+func NewSession(c *gin.Context, userID string, options ...supertokens.NewSessionOption) (NewSessionResult, error) {
+	options = append([]supertokens.NewSessionOption{supertokens.WithRequest(c.Request)}, options...)
+	result, err := supertokens.NewSessionWithContext(c.Request.Context(), c.Writer, userID, options...)
+	if err != nil {
+		return NewSessionResult{}, err
+	}
+	return NewSessionResult{
+		Session: Session{
+			actualSession: &result.Session,
+		},
+		AccessTokenExpiry:    result.AccessTokenExpiry,
+		RefreshTokenExpiry:   result.RefreshTokenExpiry,
+		IDRefreshTokenExpiry: result.IDRefreshTokenExpiry,
+		AntiCsrfToken:        result.AntiCsrfToken,
+	}, nil
+}
+
 // GetSession function used to verify a session
 func GetSession(c *gin.Context, doAntiCsrfCheck bool) (Session, error) {
 	actualSession, err := supertokens.GetSession(c.Writer, c.Request, doAntiCsrfCheck)
@@ -73,6 +155,18 @@ func GetSession(c *gin.Context, doAntiCsrfCheck bool) (Session, error) {
 	}, nil
 }
 
+// GetOptionalSession function used to verify a session if the request has one. It returns nil, and no error,
+// if the request has no session tokens
+func GetOptionalSession(c *gin.Context, doAntiCsrfCheck bool) (*Session, error) {
+	actualSession, err := supertokens.GetOptionalSession(c.Writer, c.Request, doAntiCsrfCheck)
+	if err != nil || actualSession == nil {
+		return nil, err
+	}
+	return &Session{
+		actualSession: actualSession,
+	}, nil
+}
+
 // RefreshSession function used to refresh a session
 func RefreshSession(c *gin.Context) (Session, error) {
 	actualSession, err := supertokens.RefreshSession(c.Writer, c.Request)
@@ -124,11 +218,21 @@ func GetCORSAllowedHeaders() []string {
 	return supertokens.GetCORSAllowedHeaders()
 }
 
+// GetCORSExposedHeaders function is used to get the keys of the response headers the frontend reads
+func GetCORSExposedHeaders() []string {
+	return supertokens.GetCORSExposedHeaders()
+}
+
 // GetJWTPayload function used to get jwt payload for the given handle
 func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
 	return supertokens.GetJWTPayload(sessionHandle)
 }
 
+// GetSessionInformation function used to get the session data and jwt payload for the given handle
+func GetSessionInformation(sessionHandle string) (supertokens.SessionInformation, error) {
+	return supertokens.GetSessionInformation(sessionHandle)
+}
+
 // UpdateJWTPayload function used to update jwt payload for the given handle
 func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}) error {
 	return supertokens.UpdateJWTPayload(sessionHandle, newJWTPayload)
@@ -154,6 +258,31 @@ func OnGeneralError(handler func(error, http.ResponseWriter)) {
 	supertokens.OnGeneralError(handler)
 }
 
+// OnOfflineVerificationError function to override default behaviour of handling access tokens that cannot be verified with the pinned key
+func OnOfflineVerificationError(handler func(error, http.ResponseWriter)) {
+	supertokens.OnOfflineVerificationError(handler)
+}
+
+// OnInvalidClaim function to override default behaviour of handling sessions whose jwt payload fails claim validation
+func OnInvalidClaim(handler func(error, http.ResponseWriter)) {
+	supertokens.OnInvalidClaim(handler)
+}
+
+// OnSessionEvent function to be notified of session events of the given types, or of all of them if no type is given
+func OnSessionEvent(handler func(supertokens.SessionEvent), types ...supertokens.SessionEventType) {
+	supertokens.OnSessionEvent(handler, types...)
+}
+
+// OnSigningKeyRotated function to be notified whenever the JWT signing key changes
+func OnSigningKeyRotated(handler func(supertokens.SigningKeyRotation)) {
+	supertokens.OnSigningKeyRotated(handler)
+}
+
+// GetCoreHostsStatus function used to get the health of every configured core
+func GetCoreHostsStatus() []supertokens.CoreHostStatus {
+	return supertokens.GetCoreHostsStatus()
+}
+
 // GetSessionFromRequest returns the verified session object if present, otherwise returns nil
 func GetSessionFromRequest(c *gin.Context) *Session {
 	value, exists := c.Get(sessionContext)
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+// Package supertokensotel records the metrics of the SuperTokens SDK with OpenTelemetry
+package supertokensotel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics records the metrics of the SDK as OpenTelemetry counters and histograms.
+// Set it as supertokens.ConfigMap.Metrics
+type Metrics struct {
+	meter      metric.Meter
+	counters   map[string]metric.Int64Counter
+	histograms map[string]metric.Float64Histogram
+	lock       sync.Mutex
+}
+
+var _ supertokens.Metrics = (*Metrics)(nil)
+
+// NewMetrics creates the instruments with the given meter, for example otel.Meter("supertokens")
+func NewMetrics(meter metric.Meter) *Metrics {
+	return &Metrics{
+		meter:      meter,
+		counters:   map[string]metric.Int64Counter{},
+		histograms: map[string]metric.Float64Histogram{},
+	}
+}
+
+// IncCounter adds one to the counter with the given name
+func (m *Metrics) IncCounter(name string, attributes map[string]string) {
+	counter, err := m.getCounter(name)
+	if err != nil {
+		otel.Handle(err)
+		return
+	}
+	counter.Add(context.Background(), 1, metric.WithAttributes(getAttributes(attributes)...))
+}
+
+// RecordDuration records the duration in seconds in the histogram with the given name
+func (m *Metrics) RecordDuration(name string, duration time.Duration, attributes map[string]string) {
+	histogram, err := m.getHistogram(name)
+	if err != nil {
+		otel.Handle(err)
+		return
+	}
+	histogram.Record(context.Background(), duration.Seconds(), metric.WithAttributes(getAttributes(attributes)...))
+}
+
+func (m *Metrics) getCounter(name string) (metric.Int64Counter, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if counter, ok := m.counters[name]; ok {
+		return counter, nil
+	}
+	counter, err := m.meter.Int64Counter(name)
+	if err != nil {
+		return nil, err
+	}
+	m.counters[name] = counter
+	return counter, nil
+}
+
+func (m *Metrics) getHistogram(name string) (metric.Float64Histogram, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if histogram, ok := m.histograms[name]; ok {
+		return histogram, nil
+	}
+	histogram, err := m.meter.Float64Histogram(name, metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	m.histograms[name] = histogram
+	return histogram, nil
+}
+
+func getAttributes(attributes map[string]string) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		result = append(result, attribute.String(key, value))
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package supertokensotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	var data metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &data))
+	result := map[string]metricdata.Aggregation{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			result[m.Name] = m.Data
+		}
+	}
+	return result
+}
+
+func Test_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+	metrics := NewMetrics(provider.Meter("supertokens"))
+
+	metrics.IncCounter(supertokens.MetricSessionEvents, map[string]string{"event": "created"})
+	metrics.IncCounter(supertokens.MetricSessionEvents, map[string]string{"event": "created"})
+	metrics.IncCounter(supertokens.MetricSessionEvents, map[string]string{"event": "revoked"})
+	metrics.RecordDuration(supertokens.MetricCoreRequestDuration, 250*time.Millisecond, map[string]string{"path": "/session"})
+
+	data := collect(t, reader)
+
+	sum, ok := data[supertokens.MetricSessionEvents].(metricdata.Sum[int64])
+	assert.True(t, ok)
+	counts := map[string]int64{}
+	for _, point := range sum.DataPoints {
+		event, _ := point.Attributes.Value(attribute.Key("event"))
+		counts[event.AsString()] = point.Value
+	}
+	assert.Equal(t, map[string]int64{"created": 2, "revoked": 1}, counts)
+
+	histogram, ok := data[supertokens.MetricCoreRequestDuration].(metricdata.Histogram[float64])
+	assert.True(t, ok)
+	assert.Len(t, histogram.DataPoints, 1)
+	point := histogram.DataPoints[0]
+	assert.Equal(t, uint64(1), point.Count)
+	assert.Equal(t, 0.25, point.Sum)
+	path, _ := point.Attributes.Value(attribute.Key("path"))
+	assert.Equal(t, "/session", path.AsString())
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package supertokensotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer traces the SDK with OpenTelemetry and sends the W3C trace context to the cores.
+// Set it as supertokens.ConfigMap.Tracer
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+var _ supertokens.Tracer = (*Tracer)(nil)
+
+// NewTracer uses the given tracer, for example otel.Tracer("supertokens"), and the global propagator
+func NewTracer(tracer trace.Tracer) *Tracer {
+	return NewTracerWithPropagator(tracer, otel.GetTextMapPropagator())
+}
+
+// NewTracerWithPropagator uses the given tracer and propagator, for example propagation.TraceContext{}
+func NewTracerWithPropagator(tracer trace.Tracer, propagator propagation.TextMapPropagator) *Tracer {
+	return &Tracer{
+		tracer:     tracer,
+		propagator: propagator,
+	}
+}
+
+// StartSpan starts an OpenTelemetry span as a child of the span in ctx
+func (t *Tracer) StartSpan(ctx context.Context, name string, kind core.SpanKind) (context.Context, core.Span) {
+	spanKind := trace.SpanKindInternal
+	if kind == core.SpanKindClient {
+		spanKind = trace.SpanKindClient
+	}
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(spanKind))
+	return ctx, otelSpan{span: span}
+}
+
+// Inject adds the trace context of ctx to the headers of a query to a core
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case int64:
+		s.span.SetAttributes(attribute.Int64(key, v))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, v))
+	case float64:
+		s.span.SetAttributes(attribute.Float64(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
@@ -0,0 +1,56 @@
+package supertokensotel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_Tracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+	tracer := NewTracerWithPropagator(provider.Tracer("supertokens"), propagation.TraceContext{})
+
+	ctx, parent := tracer.StartSpan(context.Background(), "supertokens.GetSession", core.SpanKindInternal)
+	parent.SetAttribute("userId", "user")
+	parent.SetAttribute("attempt", 2)
+	parent.SetAttribute("freshlyRefreshed", true)
+	_, child := tracer.StartSpan(ctx, "supertokens.core.query", core.SpanKindClient)
+	child.RecordError(errors.New("core unreachable"))
+	child.End()
+	parent.End()
+
+	header := http.Header{}
+	tracer.Inject(ctx, header)
+	assert.Contains(t, header.Get("traceparent"), trace.SpanContextFromContext(ctx).TraceID().String())
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	childSpan, parentSpan := spans[0], spans[1]
+
+	assert.Equal(t, "supertokens.GetSession", parentSpan.Name())
+	assert.Equal(t, trace.SpanKindInternal, parentSpan.SpanKind())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("userId", "user"),
+		attribute.Int("attempt", 2),
+		attribute.Bool("freshlyRefreshed", true),
+	}, parentSpan.Attributes())
+
+	assert.Equal(t, "supertokens.core.query", childSpan.Name())
+	assert.Equal(t, trace.SpanKindClient, childSpan.SpanKind())
+	assert.Equal(t, parentSpan.SpanContext().SpanID(), childSpan.Parent().SpanID())
+	assert.Equal(t, codes.Error, childSpan.Status().Code)
+	assert.Equal(t, "core unreachable", childSpan.Status().Description)
+	assert.Len(t, childSpan.Events(), 1)
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"net/http"
+
+	"github.com/supertokens/supertokens-go/supertokens/claims"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// RequireClaims returns a middleware that checks the JWT payload of the session set by Middleware.
+// Invalid claims are answered by the OnInvalidClaim error handler, with a 403 by default, and requests without
+// a session by the OnGeneralError error handler, with a 500
+func RequireClaims(validators ...claims.Validator) func(http.Handler) http.Handler {
+	return defaultClient.RequireClaims(validators...)
+}
+
+// RequireClaims returns a middleware that checks the JWT payload of the session set by the Middleware of this client.
+// Invalid claims are answered by the OnInvalidClaim error handler, with a 403 by default, and requests without
+// a session by the OnGeneralError error handler, with a 500
+func (client *Client) RequireClaims(validators ...claims.Validator) func(http.Handler) http.Handler {
+	return func(theirHandler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := CheckRequiredClaims(GetSessionFromRequest(r), validators...); err != nil {
+				client.HandleErrorAndRespond(err, w)
+				return
+			}
+			theirHandler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CheckRequiredClaims checks the claims of the session set by Middleware, for RequireClaims and the framework adapters.
+// A nil session means that Middleware was not used before RequireClaims, which is a mistake in the server and not
+// something the frontend can fix, so an errors.GeneralError is returned
+func CheckRequiredClaims(session *Session, validators ...claims.Validator) error {
+	if session == nil {
+		return errors.GeneralError{
+			Msg:    "No session to check the claims of. Please use RequireClaims after Middleware",
+			Reason: errors.ReasonMissingMiddleware,
+		}
+	}
+	return session.ValidateClaims(validators...)
+}
+
+// ValidateClaims function used to check the jwt payload of this session. It returns an errors.InvalidClaimError
+// if a claim is invalid. Validators that read the request get nil unless the session was created with WithRequest
+// or verified by GetSession
+func (session *Session) ValidateClaims(validators ...claims.Validator) error {
+	return validateClaims(session.request, session.GetJWTPayload(), validators)
+}
+
+func validateClaims(r *http.Request, payload map[string]interface{}, validators []claims.Validator) error {
+	claimValidationErrors := claims.Validate(r, payload, validators...)
+	if len(claimValidationErrors) == 0 {
+		return nil
+	}
+	return errors.InvalidClaimError{
+		Msg:                   "Claim validation failed for " + claimValidationErrors[0].ID,
+		ClaimValidationErrors: claimValidationErrors,
+		Reason:                errors.ReasonInvalidClaim,
+	}
+}
+
+// getClaimValidators returns the validators the middleware checks every session with
+func (client *Client) getClaimValidators(options MiddlewareOptions) []claims.Validator {
+	if options.OverrideGlobalClaimValidators != nil {
+		return options.OverrideGlobalClaimValidators(client.config.ClaimValidators)
+	}
+	return client.config.ClaimValidators
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package claims checks the JWT payload of verified sessions, for use with supertokens.RequireClaims
+package claims
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// Validator checks a claim of the JWT payload of a session
+type Validator struct {
+	// ID names the claim in the errors. The validators of this package use the key of the JWT payload they read
+	ID string
+	// Validate returns nil if the claim is valid, or else the reason it is not. r is the request being authorized
+	Validate func(r *http.Request, payload map[string]interface{}) map[string]interface{}
+}
+
+// Validate runs the validators on the JWT payload and returns the claims that are invalid
+func Validate(r *http.Request, payload map[string]interface{}, validators ...Validator) []errors.ClaimValidationError {
+	var claimValidationErrors []errors.ClaimValidationError
+	for _, validator := range validators {
+		if reason := validator.Validate(r, payload); reason != nil {
+			claimValidationErrors = append(claimValidationErrors, errors.ClaimValidationError{
+				ID:     validator.ID,
+				Reason: reason,
+			})
+		}
+	}
+	return claimValidationErrors
+}
+
+// Exists checks that the JWT payload has the key
+func Exists(key string) Validator {
+	return Validator{
+		ID: key,
+		Validate: func(_ *http.Request, payload map[string]interface{}) map[string]interface{} {
+			if _, ok := payload[key]; !ok {
+				return map[string]interface{}{"message": "value does not exist"}
+			}
+			return nil
+		},
+	}
+}
+
+// Equals checks that the value of the key in the JWT payload is value
+func Equals(key string, value interface{}) Validator {
+	return EqualsRequestValue(key, func(*http.Request) interface{} {
+		return value
+	})
+}
+
+// EqualsRequestValue checks that the value of the key in the JWT payload is the one returned by value for the
+// request, for example an id read from the URL
+func EqualsRequestValue(key string, value func(r *http.Request) interface{}) Validator {
+	return Validator{
+		ID: key,
+		Validate: func(r *http.Request, payload map[string]interface{}) map[string]interface{} {
+			expected := value(r)
+			actual, ok := payload[key]
+			if !ok {
+				return map[string]interface{}{"message": "value does not exist", "expectedValue": expected}
+			}
+			if !jsonEqual(actual, expected) {
+				return map[string]interface{}{"message": "wrong value", "expectedValue": expected, "actualValue": actual}
+			}
+			return nil
+		},
+	}
+}
+
+// Includes checks that the value of the key in the JWT payload is an array that contains value
+func Includes(key string, value interface{}) Validator {
+	return Validator{
+		ID: key,
+		Validate: func(_ *http.Request, payload map[string]interface{}) map[string]interface{} {
+			actual, ok := payload[key]
+			if !ok {
+				return map[string]interface{}{"message": "value does not exist", "expectedToInclude": value}
+			}
+			array, ok := actual.([]interface{})
+			if !ok {
+				return map[string]interface{}{"message": "value is not an array", "expectedToInclude": value, "actualValue": actual}
+			}
+			for _, element := range array {
+				if jsonEqual(element, value) {
+					return nil
+				}
+			}
+			return map[string]interface{}{"message": "wrong value", "expectedToInclude": value, "actualValue": actual}
+		},
+	}
+}
+
+// HasRole checks that the role key of the JWT payload is the role, or is an array that contains it
+func HasRole(role string) Validator {
+	return Validator{
+		ID: "role",
+		Validate: func(r *http.Request, payload map[string]interface{}) map[string]interface{} {
+			if _, ok := payload["role"].([]interface{}); ok {
+				return Includes("role", role).Validate(r, payload)
+			}
+			return Equals("role", role).Validate(r, payload)
+		},
+	}
+}
+
+// jsonEqual compares a value decoded from JSON with any value, so that for example the number 1 equals float64(1)
+func jsonEqual(decoded interface{}, value interface{}) bool {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	var normalized interface{}
+	if json.Unmarshal(encoded, &normalized) != nil {
+		return false
+	}
+	return reflect.DeepEqual(decoded, normalized)
+}
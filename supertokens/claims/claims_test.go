@@ -0,0 +1,41 @@
+package claims
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validators(t *testing.T) {
+	payload := map[string]interface{}{
+		"role":   "admin",
+		"plan":   "pro",
+		"seats":  float64(5),
+		"scopes": []interface{}{"read", "write"},
+		"org_id": "acme",
+	}
+	r := httptest.NewRequest("GET", "/orgs?org=acme", nil)
+
+	assert.Empty(t, Validate(r, payload,
+		Exists("plan"),
+		Equals("plan", "pro"),
+		Equals("seats", 5),
+		Includes("scopes", "write"),
+		HasRole("admin"),
+		EqualsRequestValue("org_id", func(r *http.Request) interface{} { return r.URL.Query().Get("org") }),
+	))
+
+	claimValidationErrors := Validate(r, payload, Equals("plan", "free"), Includes("scopes", "admin"), Exists("team"))
+	assert.Len(t, claimValidationErrors, 3)
+	assert.Equal(t, "plan", claimValidationErrors[0].ID)
+	assert.Equal(t, map[string]interface{}{"message": "wrong value", "expectedValue": "free", "actualValue": "pro"},
+		claimValidationErrors[0].Reason)
+	assert.Equal(t, "scopes", claimValidationErrors[1].ID)
+	assert.Equal(t, "team", claimValidationErrors[2].ID)
+
+	assert.Len(t, Validate(r, payload, Includes("plan", "pro")), 1, "plan is not an array")
+	assert.Len(t, Validate(r, payload, HasRole("user")), 1)
+	assert.Empty(t, Validate(r, map[string]interface{}{"role": []interface{}{"user", "admin"}}, HasRole("admin")))
+}
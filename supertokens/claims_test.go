@@ -0,0 +1,111 @@
+package supertokens
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+	"github.com/supertokens/supertokens-go/supertokens/supertokenstest"
+)
+
+func newClaimsTestRequest(t *testing.T, client *Client, jwtPayload map[string]interface{}) *http.Request {
+	w := httptest.NewRecorder()
+	_, err := client.CreateNewSession(w, "user", jwtPayload)
+	assert.NoError(t, err)
+	request := httptest.NewRequest("GET", "/orgs/acme", nil)
+	for _, cookie := range w.Result().Cookies() {
+		request.AddCookie(cookie)
+	}
+	return request
+}
+
+func Test_RequireClaims(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	handler := client.MiddlewareWithOptions(client.RequireClaims(claims.HasRole("admin"), claims.Equals("plan", "pro"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})), MiddlewareOptions{})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newClaimsTestRequest(t, client, map[string]interface{}{"role": "admin", "plan": "pro"}))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "ok", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newClaimsTestRequest(t, client, map[string]interface{}{"role": "admin", "plan": "free"}))
+	assert.Equal(t, 403, recorder.Code)
+	var body struct {
+		Message               string
+		ClaimValidationErrors []errors.ClaimValidationError
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "invalid claim", body.Message)
+	assert.Equal(t, []errors.ClaimValidationError{{
+		ID:     "plan",
+		Reason: map[string]interface{}{"message": "wrong value", "expectedValue": "pro", "actualValue": "free"},
+	}}, body.ClaimValidationErrors)
+
+	var handledError error
+	client.OnInvalidClaim(func(err error, w http.ResponseWriter) {
+		handledError = err
+		w.WriteHeader(404)
+	})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newClaimsTestRequest(t, client, map[string]interface{}{"role": "user", "plan": "pro"}))
+	assert.Equal(t, 404, recorder.Code)
+	assert.True(t, errors.IsInvalidClaimError(handledError))
+	assert.Equal(t, errors.ReasonInvalidClaim, errors.GetReason(handledError))
+
+	recorder = httptest.NewRecorder()
+	client.RequireClaims(claims.Exists("role"))(http.NotFoundHandler()).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, 500, recorder.Code, "using RequireClaims without the middleware is a server error")
+
+	err = CheckRequiredClaims(nil, claims.Exists("role"))
+	assert.True(t, errors.IsGeneralError(err))
+	assert.Equal(t, errors.ReasonMissingMiddleware, errors.GetReason(err))
+}
+
+func Test_Middleware_GlobalClaimValidators(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	orgFromURL := claims.EqualsRequestValue("org_id", func(r *http.Request) interface{} {
+		return r.URL.Path[len("/orgs/"):]
+	})
+	client, err := New(ConfigMap{Hosts: core.URL(), ClaimValidators: []claims.Validator{orgFromURL}})
+	assert.NoError(t, err)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	recorder := httptest.NewRecorder()
+	client.MiddlewareWithOptions(ok, MiddlewareOptions{}).
+		ServeHTTP(recorder, newClaimsTestRequest(t, client, map[string]interface{}{"org_id": "acme"}))
+	assert.Equal(t, 200, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	client.MiddlewareWithOptions(ok, MiddlewareOptions{}).
+		ServeHTTP(recorder, newClaimsTestRequest(t, client, map[string]interface{}{"org_id": "other"}))
+	assert.Equal(t, 403, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	client.MiddlewareWithOptions(ok, MiddlewareOptions{
+		OverrideGlobalClaimValidators: func(globalClaimValidators []claims.Validator) []claims.Validator {
+			assert.Len(t, globalClaimValidators, 1)
+			return nil
+		},
+	}).ServeHTTP(recorder, newClaimsTestRequest(t, client, map[string]interface{}{"org_id": "other"}))
+	assert.Equal(t, 200, recorder.Code)
+}
+
+func Test_Session_ValidateClaims(t *testing.T) {
+	session := Session{userDataInJWT: map[string]interface{}{"role": "admin"}}
+	assert.NoError(t, session.ValidateClaims(claims.HasRole("admin")))
+	err := session.ValidateClaims(claims.HasRole("user"))
+	assert.True(t, errors.IsInvalidClaimError(err))
+}
@@ -0,0 +1,551 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// Client is a SuperTokens client with its own cores, handshake info, error handlers and cookie settings
+type Client struct {
+	instance *core.Instance
+	config   ConfigMap
+}
+
+var defaultClient = &Client{
+	instance: core.GetDefaultInstance(),
+}
+
+// New creates a client that is independent of the one used by the package level functions
+func New(config ConfigMap) (*Client, error) {
+	hosts := []string{}
+	for _, host := range strings.Split(config.Hosts, ";") {
+		if host == "" {
+			continue
+		}
+		parsed, err := url.Parse(host)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, errors.GeneralError{
+				Msg:    "Invalid SuperTokens core host: " + host + ". Hosts must be of the form http(s)://hostname:port",
+				Reason: errors.ReasonInvalidConfig,
+			}
+		}
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		return nil, errors.GeneralError{
+			Msg:    "Please provide at least one SuperTokens core host in Hosts",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	if config.CookieSameSite != "" && config.CookieSameSite != "none" &&
+		config.CookieSameSite != "lax" && config.CookieSameSite != "strict" {
+		return nil, errors.GeneralError{
+			Msg:    "CookieSameSite must be one of none, lax or strict",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	if config.TokenTransferMethod != "" && config.TokenTransferMethod != TokenTransferMethodCookie &&
+		config.TokenTransferMethod != TokenTransferMethodHeader && config.TokenTransferMethod != TokenTransferMethodAny {
+		return nil, errors.GeneralError{
+			Msg:    "TokenTransferMethod must be one of cookie, header or any",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	if config.RequestTimeout < 0 {
+		return nil, errors.GeneralError{
+			Msg:    "RequestTimeout cannot be negative",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	if config.HealthCheck != nil && (config.HealthCheck.FailureThreshold < 0 || config.HealthCheck.ProbeInterval < 0) {
+		return nil, errors.GeneralError{
+			Msg:    "HealthCheck values cannot be negative",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	if config.RetryPolicy != nil && (config.RetryPolicy.MaxRetries < 0 ||
+		config.RetryPolicy.InitialBackoff < 0 || config.RetryPolicy.MaxBackoff < 0) {
+		return nil, errors.GeneralError{
+			Msg:    "RetryPolicy values cannot be negative",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	if config.SigningKey != nil && (config.SigningKey.RefreshBefore < 0 || config.SigningKey.MinRefreshInterval < 0) {
+		return nil, errors.GeneralError{
+			Msg:    "SigningKey RefreshBefore and MinRefreshInterval cannot be negative",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	instance := core.NewInstance(config.Hosts, config.APIKey, getQuerierOptions(config))
+	instance.SetLogger(config.Logger)
+	instance.SetMetrics(config.Metrics)
+	instance.SetTracer(config.Tracer)
+	if config.SigningKey != nil {
+		instance.SetSigningKeyOptions(*config.SigningKey)
+	}
+	if err := instance.SetOfflineVerification(getOfflineVerificationOptions(config)); err != nil {
+		return nil, errors.GeneralError{
+			Msg:         "Could not load the JWT signing public key: " + err.Error(),
+			ActualError: err,
+			Reason:      errors.ReasonSigningKeyUnavailable,
+		}
+	}
+	return &Client{
+		instance: instance,
+		config:   config,
+	}, nil
+}
+
+// CreateNewSession function used to create a new SuperTokens session
+func (client *Client) CreateNewSession(response http.ResponseWriter,
+	userID string, payload ...map[string]interface{}) (Session, error) {
+	return client.CreateNewSessionWithContext(context.Background(), response, userID, payload...)
+}
+
+// CreateNewSessionWithContext function used to create a new SuperTokens session, aborting the query to the core when ctx is done
+func (client *Client) CreateNewSessionWithContext(ctx context.Context, response http.ResponseWriter,
+	userID string, payload ...map[string]interface{}) (Session, error) {
+
+	if len(payload) > 2 {
+		return Session{}, errors.GeneralError{
+			Msg:    "CreateNewSession takes at most a JWT payload and session data. Use NewSession with WithJWTPayload and WithSessionData instead",
+			Reason: errors.ReasonInvalidPayload,
+		}
+	}
+	var jwtPayload = map[string]interface{}{}
+	var sessionData = map[string]interface{}{}
+	if len(payload) == 1 && payload[0] != nil {
+		jwtPayload = payload[0]
+	} else if len(payload) == 2 {
+		if payload[0] != nil {
+			jwtPayload = payload[0]
+		}
+		if payload[1] != nil {
+			sessionData = payload[1]
+		}
+	}
+
+	session, _, err := client.createNewSession(ctx, response, nil, userID, jwtPayload, sessionData, client.getTokenTransferMethod())
+	return session, err
+}
+
+// createNewSession creates a session in the core and sends its tokens with the given transfer method.
+// request is nil if the caller did not pass it
+func (client *Client) createNewSession(ctx context.Context, response http.ResponseWriter, request *http.Request, userID string,
+	jwtPayload map[string]interface{}, sessionData map[string]interface{}, tokenTransferMethod string) (Session, core.SessionInfo, error) {
+	session, err := client.instance.CreateNewSession(ctx, userID, jwtPayload, sessionData)
+
+	if err != nil {
+		return Session{}, core.SessionInfo{}, err
+	}
+
+	client.attachSessionTokens(response, session, tokenTransferMethod)
+	client.emitSessionEvent(SessionCreated, session.UserID, session.Handle, request)
+
+	return Session{
+		accessToken:         session.AccessToken.Token,
+		sessionHandle:       session.Handle,
+		userID:              session.UserID,
+		userDataInJWT:       session.UserDataInJWT,
+		response:            response,
+		request:             request,
+		client:              client,
+		tokenTransferMethod: tokenTransferMethod,
+	}, session, nil
+}
+
+// GetSession function used to verify a session. Queries to the core are aborted when the request's context is done
+func (client *Client) GetSession(response http.ResponseWriter, request *http.Request,
+	doAntiCsrfCheck bool) (Session, error) {
+	session, err := client.getSession(response, request, doAntiCsrfCheck)
+	if err != nil {
+		client.emitSessionErrorEvent(err, request)
+	}
+	return session, err
+}
+
+// GetOptionalSession function used to verify a session if the request has one. It returns nil, and no error,
+// if the request has no session tokens. Tokens that are expired or invalid still return an error
+func (client *Client) GetOptionalSession(response http.ResponseWriter, request *http.Request,
+	doAntiCsrfCheck bool) (*Session, error) {
+	session, err := client.getSession(response, request, doAntiCsrfCheck)
+	if err != nil {
+		if isSessionMissingError(err) {
+			return nil, nil
+		}
+		client.emitSessionErrorEvent(err, request)
+		return nil, err
+	}
+	return &session, nil
+}
+
+// isSessionMissingError tells if getSession failed because the request has no session tokens at all
+func isSessionMissingError(err error) bool {
+	if !errors.IsUnauthorizedError(err) {
+		return false
+	}
+	reason := errors.GetReason(err)
+	return reason == errors.ReasonIDRefreshTokenMissing || reason == errors.ReasonAccessTokenMissing
+}
+
+func (client *Client) getSession(response http.ResponseWriter, request *http.Request,
+	doAntiCsrfCheck bool) (Session, error) {
+	saveFrontendInfoFromRequest(request)
+
+	tokenTransferMethod := TokenTransferMethodCookie
+	var accessToken *string = nil
+	if client.allowsHeaderTransfer() {
+		accessToken = getAccessTokenFromAuthorizationHeader(request)
+		tokenTransferMethod = TokenTransferMethodHeader
+	}
+
+	if accessToken == nil {
+		if !client.allowsCookieTransfer() {
+			return Session{}, errors.UnauthorizedError{
+				Msg:    "access token missing in the Authorization header",
+				Reason: errors.ReasonAccessTokenMissing,
+			}
+		}
+		tokenTransferMethod = TokenTransferMethodCookie
+
+		idRefreshToken := getIDRefreshTokenFromCookie(request)
+		if idRefreshToken == nil {
+			return Session{}, errors.UnauthorizedError{
+				Msg:    "idRefreshToken missing",
+				Reason: errors.ReasonIDRefreshTokenMissing,
+			}
+		}
+
+		accessToken = getAccessTokenFromCookie(request)
+		if accessToken == nil {
+			// maybe the access token has expired.
+			return Session{}, errors.TryRefreshTokenError{
+				Msg:    "access token missing in cookies",
+				Reason: errors.ReasonAccessTokenMissing,
+			}
+		}
+	}
+
+	antiCsrfToken := getAntiCsrfTokenFromHeaders(request)
+	// requests that send tokens in headers cannot be forged by a browser, so anti-csrf is not needed
+	doAntiCsrfCheck = doAntiCsrfCheck && tokenTransferMethod == TokenTransferMethodCookie
+
+	session, getSessionError := client.instance.GetSession(request.Context(), *accessToken, antiCsrfToken, doAntiCsrfCheck)
+
+	if getSessionError != nil {
+		if errors.IsUnauthorizedError(getSessionError) && tokenTransferMethod == TokenTransferMethodCookie {
+			handShakeInfo, handshakeInfoError := client.instance.GetHandshakeInfo(request.Context())
+			if handshakeInfoError != nil {
+				return Session{}, handshakeInfoError
+			}
+			client.clearSessionFromCookie(response,
+				handShakeInfo.CookieDomain,
+				handShakeInfo.CookieSecure,
+				handShakeInfo.AccessTokenPath,
+				handShakeInfo.RefreshTokenPath,
+				handShakeInfo.IDRefreshTokenPath,
+				handShakeInfo.CookieSameSite,
+			)
+		}
+		return Session{}, getSessionError
+	}
+
+	if session.AccessToken != nil {
+
+		attachFrontTokenInHeaders(
+			response,
+			session.UserID,
+			session.AccessToken.Expiry,
+			session.UserDataInJWT,
+		)
+
+		client.attachAccessToken(response, *session.AccessToken, tokenTransferMethod)
+		accessToken = &session.AccessToken.Token
+	}
+
+	return Session{
+		accessToken:         *accessToken,
+		response:            response,
+		request:             request,
+		sessionHandle:       session.Handle,
+		userDataInJWT:       session.UserDataInJWT,
+		userID:              session.UserID,
+		client:              client,
+		tokenTransferMethod: tokenTransferMethod,
+	}, nil
+}
+
+// RefreshSession function used to refresh a session. Queries to the core are aborted when the request's context is done
+func (client *Client) RefreshSession(response http.ResponseWriter, request *http.Request) (Session, error) {
+	session, err := client.refreshSession(response, request)
+	if err != nil {
+		client.emitSessionErrorEvent(err, request)
+	}
+	return session, err
+}
+
+func (client *Client) refreshSession(response http.ResponseWriter, request *http.Request) (Session, error) {
+	saveFrontendInfoFromRequest(request)
+	tokenTransferMethod := TokenTransferMethodCookie
+	var inputRefreshToken *string = nil
+	if client.allowsHeaderTransfer() {
+		inputRefreshToken = getRefreshTokenFromHeaders(request)
+		tokenTransferMethod = TokenTransferMethodHeader
+	}
+	if inputRefreshToken == nil && client.allowsCookieTransfer() {
+		inputRefreshToken = getRefreshTokenFromCookie(request)
+		tokenTransferMethod = TokenTransferMethodCookie
+	}
+	if inputRefreshToken == nil {
+		if !client.allowsCookieTransfer() {
+			return Session{}, errors.UnauthorizedError{
+				Msg:    "Missing refresh token in the " + refreshTokenHeaderKey + " header",
+				Reason: errors.ReasonRefreshTokenMissing,
+			}
+		}
+		return Session{}, errors.UnauthorizedError{
+			Msg:    "Missing auth tokens in cookies. Have you set the correct refresh API path in your frontend and SuperTokens config?",
+			Reason: errors.ReasonRefreshTokenMissing,
+		}
+	}
+
+	antiCsrfToken := getAntiCsrfTokenFromHeaders(request)
+	session, refreshError := client.instance.RefreshSession(request.Context(), *inputRefreshToken, antiCsrfToken)
+
+	if refreshError != nil {
+
+		if (errors.IsUnauthorizedError(refreshError) || errors.IsTokenTheftDetectedError(refreshError)) &&
+			tokenTransferMethod == TokenTransferMethodCookie {
+			handShakeInfo, handshakeInfoError := client.instance.GetHandshakeInfo(request.Context())
+			if handshakeInfoError != nil {
+				return Session{}, handshakeInfoError
+			}
+			client.clearSessionFromCookie(
+				response,
+				handShakeInfo.CookieDomain,
+				handShakeInfo.CookieSecure,
+				handShakeInfo.AccessTokenPath,
+				handShakeInfo.RefreshTokenPath,
+				handShakeInfo.IDRefreshTokenPath,
+				handShakeInfo.CookieSameSite)
+		}
+		return Session{}, refreshError
+	}
+
+	client.attachSessionTokens(response, session, tokenTransferMethod)
+	client.emitSessionEvent(SessionRefreshed, session.UserID, session.Handle, request)
+
+	return Session{
+		accessToken:         session.AccessToken.Token,
+		sessionHandle:       session.Handle,
+		userID:              session.UserID,
+		userDataInJWT:       session.UserDataInJWT,
+		response:            response,
+		request:             request,
+		client:              client,
+		tokenTransferMethod: tokenTransferMethod,
+	}, nil
+}
+
+// RevokeAllSessionsForUser function used to revoke all sessions for a user
+func (client *Client) RevokeAllSessionsForUser(userID string) ([]string, error) {
+	return client.RevokeAllSessionsForUserWithContext(context.Background(), userID)
+}
+
+// RevokeAllSessionsForUserWithContext function used to revoke all sessions for a user, aborting the query to the core when ctx is done
+func (client *Client) RevokeAllSessionsForUserWithContext(ctx context.Context, userID string) ([]string, error) {
+	revoked, err := client.instance.RevokeAllSessionsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	client.emitSessionsRevoked(userID, revoked, nil)
+	return revoked, nil
+}
+
+// GetAllSessionHandlesForUser function used to get all sessions for a user
+func (client *Client) GetAllSessionHandlesForUser(userID string) ([]string, error) {
+	return client.GetAllSessionHandlesForUserWithContext(context.Background(), userID)
+}
+
+// GetAllSessionHandlesForUserWithContext function used to get all sessions for a user, aborting the query to the core when ctx is done
+func (client *Client) GetAllSessionHandlesForUserWithContext(ctx context.Context, userID string) ([]string, error) {
+	return client.instance.GetAllSessionHandlesForUser(ctx, userID)
+}
+
+// RevokeSession function used to revoke a specific session
+func (client *Client) RevokeSession(sessionHandle string) (bool, error) {
+	return client.RevokeSessionWithContext(context.Background(), sessionHandle)
+}
+
+// RevokeSessionWithContext function used to revoke a specific session, aborting the query to the core when ctx is done
+func (client *Client) RevokeSessionWithContext(ctx context.Context, sessionHandle string) (bool, error) {
+	success, err := client.instance.RevokeSession(ctx, sessionHandle)
+	if err != nil {
+		return false, err
+	}
+	if success {
+		client.emitSessionEvent(SessionRevoked, "", sessionHandle, nil)
+	}
+	return success, nil
+}
+
+// RevokeMultipleSessions function used to revoke a list of sessions
+func (client *Client) RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
+	return client.RevokeMultipleSessionsWithContext(context.Background(), sessionHandles)
+}
+
+// RevokeMultipleSessionsWithContext function used to revoke a list of sessions, aborting the query to the core when ctx is done
+func (client *Client) RevokeMultipleSessionsWithContext(ctx context.Context, sessionHandles []string) ([]string, error) {
+	revoked, err := client.instance.RevokeMultipleSessions(ctx, sessionHandles)
+	if err != nil {
+		return nil, err
+	}
+	client.emitSessionsRevoked("", revoked, nil)
+	return revoked, nil
+}
+
+// GetSessionData function used to get session data for the given handle
+func (client *Client) GetSessionData(sessionHandle string) (map[string]interface{}, error) {
+	return client.GetSessionDataWithContext(context.Background(), sessionHandle)
+}
+
+// GetSessionDataWithContext function used to get session data for the given handle, aborting the query to the core when ctx is done
+func (client *Client) GetSessionDataWithContext(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	return client.instance.GetSessionData(ctx, sessionHandle)
+}
+
+// UpdateSessionData function used to update session data for the given handle
+func (client *Client) UpdateSessionData(sessionHandle string, newSessionData map[string]interface{}) error {
+	return client.UpdateSessionDataWithContext(context.Background(), sessionHandle, newSessionData)
+}
+
+// UpdateSessionDataWithContext function used to update session data for the given handle, aborting the query to the core when ctx is done
+func (client *Client) UpdateSessionDataWithContext(ctx context.Context, sessionHandle string, newSessionData map[string]interface{}) error {
+	return client.instance.UpdateSessionData(ctx, sessionHandle, newSessionData)
+}
+
+// SetRelevantHeadersForOptionsAPI sets the CORS headers of an OPTIONS request for the headers this client reads
+func (client *Client) SetRelevantHeadersForOptionsAPI(response http.ResponseWriter) {
+	client.setRelevantHeadersForOptionsAPI(response)
+}
+
+// GetCORSAllowedHeaders returns the keys of the request headers this client reads. With the header or any
+// token transfer method, these include authorization and st-refresh-token
+func (client *Client) GetCORSAllowedHeaders() []string {
+	return client.getCORSAllowedHeaders()
+}
+
+// GetCORSExposedHeaders returns the keys of the response headers this client sets for the frontend. With the
+// header or any token transfer method, these include st-access-token and st-refresh-token
+func (client *Client) GetCORSExposedHeaders() []string {
+	return client.getCORSExposedHeaders()
+}
+
+// GetJWTPayload function used to get jwt payload for the given handle
+func (client *Client) GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
+	return client.GetJWTPayloadWithContext(context.Background(), sessionHandle)
+}
+
+// GetJWTPayloadWithContext function used to get jwt payload for the given handle, aborting the query to the core when ctx is done
+func (client *Client) GetJWTPayloadWithContext(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	return client.instance.GetJWTPayload(ctx, sessionHandle)
+}
+
+// GetSessionInformation function used to get the session data and jwt payload for the given handle
+func (client *Client) GetSessionInformation(sessionHandle string) (SessionInformation, error) {
+	return client.GetSessionInformationWithContext(context.Background(), sessionHandle)
+}
+
+// GetSessionInformationWithContext function used to get the session data and jwt payload for the given handle,
+// aborting the queries to the core when ctx is done
+func (client *Client) GetSessionInformationWithContext(ctx context.Context, sessionHandle string) (SessionInformation, error) {
+	sessionData, err := client.instance.GetSessionData(ctx, sessionHandle)
+	if err != nil {
+		return SessionInformation{}, err
+	}
+	jwtPayload, err := client.instance.GetJWTPayload(ctx, sessionHandle)
+	if err != nil {
+		return SessionInformation{}, err
+	}
+	return SessionInformation{
+		SessionHandle: sessionHandle,
+		SessionData:   sessionData,
+		JWTPayload:    jwtPayload,
+	}, nil
+}
+
+// UpdateJWTPayload function used to update jwt payload for the given handle
+func (client *Client) UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}) error {
+	return client.UpdateJWTPayloadWithContext(context.Background(), sessionHandle, newJWTPayload)
+}
+
+// UpdateJWTPayloadWithContext function used to update jwt payload for the given handle, aborting the query to the core when ctx is done
+func (client *Client) UpdateJWTPayloadWithContext(ctx context.Context, sessionHandle string, newJWTPayload map[string]interface{}) error {
+	return client.instance.UpdateJWTPayload(ctx, sessionHandle, newJWTPayload)
+}
+
+// OnTokenTheftDetected function to override default behaviour of handling token thefts
+func (client *Client) OnTokenTheftDetected(handler func(string, string, http.ResponseWriter)) {
+	client.instance.GetErrorHandlers().OnTokenTheftDetectedErrorHandler = handler
+}
+
+// OnUnauthorized function to override default behaviour of handling Unauthorized error
+func (client *Client) OnUnauthorized(handler func(error, http.ResponseWriter)) {
+	client.instance.GetErrorHandlers().OnUnauthorizedErrorHandler = handler
+}
+
+// OnTryRefreshToken function to override default behaviour of handling try refresh token errors
+func (client *Client) OnTryRefreshToken(handler func(error, http.ResponseWriter)) {
+	client.instance.GetErrorHandlers().OnTryRefreshTokenErrorHandler = handler
+}
+
+// OnGeneralError function to override default behaviour of handling general errors
+func (client *Client) OnGeneralError(handler func(error, http.ResponseWriter)) {
+	client.instance.GetErrorHandlers().OnGeneralErrorHandler = handler
+}
+
+// GetCoreHostsStatus function used to get the health of every core this client queries
+func (client *Client) GetCoreHostsStatus() []CoreHostStatus {
+	return client.instance.GetQuerier().GetHostsStatus()
+}
+
+// OnInvalidClaim function to override default behaviour of handling sessions whose jwt payload fails claim validation
+func (client *Client) OnInvalidClaim(handler func(error, http.ResponseWriter)) {
+	client.instance.GetErrorHandlers().OnInvalidClaimErrorHandler = handler
+}
+
+// OnOfflineVerificationError function to override default behaviour of handling access tokens that cannot be verified with the pinned key
+func (client *Client) OnOfflineVerificationError(handler func(error, http.ResponseWriter)) {
+	client.instance.GetErrorHandlers().OnOfflineVerificationErrorHandler = handler
+}
+
+// OnSigningKeyRotated function to be notified whenever the JWT signing key changes
+func (client *Client) OnSigningKeyRotated(handler func(SigningKeyRotation)) {
+	client.instance.OnSigningKeyRotation(handler)
+}
+
+// Close stops the background work of this client, like refreshing the JWT signing key
+func (client *Client) Close() {
+	client.instance.Close()
+}
@@ -0,0 +1,146 @@
+package supertokens
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func Test_New_InvalidConfig(t *testing.T) {
+	_, err := New(ConfigMap{})
+	assert.Error(t, err)
+
+	_, err = New(ConfigMap{Hosts: "localhost:3567"})
+	assert.Error(t, err)
+
+	_, err = New(ConfigMap{Hosts: "http://localhost:3567", CookieSameSite: "invalid"})
+	assert.Error(t, err)
+
+	_, err = New(ConfigMap{Hosts: "http://localhost:3567", JWTSigningPublicKeyPath: "/does/not/exist"})
+	assert.Error(t, err)
+
+	_, err = New(ConfigMap{Hosts: "http://localhost:3567", TokenTransferMethod: "query"})
+	assert.Error(t, err)
+
+	client, err := New(ConfigMap{Hosts: "http://localhost:3567;https://try.supertokens.io/"})
+	assert.NoError(t, err)
+	assert.NotNil(t, client)
+}
+
+func Test_New_IndependentClients(t *testing.T) {
+	publicClient, err := New(ConfigMap{Hosts: "http://localhost:3567", CookieDomain: "example.com"})
+	assert.NoError(t, err)
+	adminClient, err := New(ConfigMap{Hosts: "http://localhost:3568", CookieDomain: "admin.example.com"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, publicClient.instance, adminClient.instance)
+
+	w := httptest.NewRecorder()
+	publicClient.attachAccessTokenToCookie(w, "token", 0, nil, false, "/", "lax")
+	assert.True(t, strings.Contains(w.Header().Get("Set-Cookie"), "Domain=example.com"))
+
+	w = httptest.NewRecorder()
+	adminClient.attachAccessTokenToCookie(w, "token", 0, nil, false, "/", "lax")
+	assert.True(t, strings.Contains(w.Header().Get("Set-Cookie"), "Domain=admin.example.com"))
+}
+
+func Test_Middleware_OfflineVerification_DoesNotQueryCore(t *testing.T) {
+	client, err := New(ConfigMap{Hosts: "http://localhost:1", JWTSigningPublicKey: "key"})
+	assert.NoError(t, err)
+
+	request := httptest.NewRequest("GET", "/user", nil)
+	request.AddCookie(&http.Cookie{Name: idRefreshTokenCookieKey, Value: "idRefreshToken"})
+	request.AddCookie(&http.Cookie{Name: accessTokenCookieKey, Value: "accessToken"})
+	recorder := httptest.NewRecorder()
+	client.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not have been called")
+	})(recorder, request)
+	assert.Equal(t, 401, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "Unauthorized"))
+}
+
+// createSignedAccessToken signs an access token the way the core does and returns it with the public key to verify it
+func createSignedAccessToken(t *testing.T, antiCsrfToken string) (string, string) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.NoError(t, err)
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	payload, err := json.Marshal(map[string]interface{}{
+		"sessionHandle":     "handle",
+		"userId":            "userId",
+		"refreshTokenHash1": "hash",
+		"userData":          map[string]interface{}{},
+		"antiCsrfToken":     antiCsrfToken,
+		"expiryTime":        now + 3600000,
+		"timeCreated":       now,
+	})
+	assert.NoError(t, err)
+	header := "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCIsInZlcnNpb24iOiIyIn0="
+	unsigned := header + "." + base64.StdEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(der), unsigned + "." + base64.StdEncoding.EncodeToString(signature)
+}
+
+func Test_GetSession_HeaderTransfer(t *testing.T) {
+	publicKey, accessToken := createSignedAccessToken(t, "csrf")
+	client, err := New(ConfigMap{
+		Hosts:               "http://localhost:1",
+		JWTSigningPublicKey: publicKey,
+		TokenTransferMethod: TokenTransferMethodHeader,
+	})
+	assert.NoError(t, err)
+
+	request := httptest.NewRequest("POST", "/user", nil)
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	session, err := client.GetSession(httptest.NewRecorder(), request, true)
+	assert.NoError(t, err, "anti-csrf should be skipped for tokens sent in headers")
+	assert.Equal(t, "userId", session.GetUserID())
+
+	request = httptest.NewRequest("POST", "/user", nil)
+	request.AddCookie(&http.Cookie{Name: idRefreshTokenCookieKey, Value: "idRefreshToken"})
+	request.AddCookie(&http.Cookie{Name: accessTokenCookieKey, Value: accessToken})
+	_, err = client.GetSession(httptest.NewRecorder(), request, true)
+	assert.True(t, errors.IsUnauthorizedError(err), "cookies should be ignored in header mode")
+
+	_, err = client.RefreshSession(httptest.NewRecorder(), httptest.NewRequest("POST", "/refresh", nil))
+	assert.True(t, errors.IsUnauthorizedError(err))
+	assert.Equal(t, errors.ReasonRefreshTokenMissing, errors.GetReason(err))
+}
+
+func Test_GetSession_CookieTransferIgnoresAuthorizationHeader(t *testing.T) {
+	publicKey, accessToken := createSignedAccessToken(t, "csrf")
+	client, err := New(ConfigMap{Hosts: "http://localhost:1", JWTSigningPublicKey: publicKey})
+	assert.NoError(t, err)
+
+	request := httptest.NewRequest("GET", "/user", nil)
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	_, err = client.GetSession(httptest.NewRecorder(), request, false)
+	assert.Equal(t, errors.ReasonIDRefreshTokenMissing, errors.GetReason(err))
+
+	anyClient, err := New(ConfigMap{
+		Hosts:               "http://localhost:1",
+		JWTSigningPublicKey: publicKey,
+		TokenTransferMethod: TokenTransferMethodAny,
+	})
+	assert.NoError(t, err)
+	request = httptest.NewRequest("POST", "/user", nil)
+	request.AddCookie(&http.Cookie{Name: idRefreshTokenCookieKey, Value: "idRefreshToken"})
+	request.AddCookie(&http.Cookie{Name: accessTokenCookieKey, Value: url.QueryEscape(accessToken)})
+	_, err = anyClient.GetSession(httptest.NewRecorder(), request, true)
+	assert.Equal(t, errors.ReasonAntiCsrfCheckFailed, errors.GetReason(err), "anti-csrf should be checked for tokens sent in cookies")
+}
@@ -41,34 +41,43 @@ const frontendSDKVersionHeaderKey = "supertokens-sdk-version"
 
 const frontTokenHeaderKey = "front-token"
 
+const authorizationHeaderKey = "authorization"
+const accessTokenHeaderKey = "st-access-token"
+const refreshTokenHeaderKey = "st-refresh-token"
+
+// Values of ConfigMap.TokenTransferMethod
+const (
+	// TokenTransferMethodCookie sends and reads tokens in cookies. This is the default
+	TokenTransferMethodCookie = "cookie"
+	// TokenTransferMethodHeader sends tokens in the st-access-token and st-refresh-token response headers,
+	// and reads them from the Authorization: Bearer and st-refresh-token request headers
+	TokenTransferMethodHeader = "header"
+	// TokenTransferMethodAny reads tokens from headers if present, otherwise from cookies
+	TokenTransferMethodAny = "any"
+)
+
 type TokenInfo struct {
 	Uid string                 `json:"uid"`
 	Ate uint64                 `json:"ate"`
 	Up  map[string]interface{} `json:"up"`
 }
 
-var configMap *ConfigMap = nil
-
-func configCookieAndHeaders(config ConfigMap) {
-	configMap = &config
-}
-
-func attachAccessTokenToCookie(response http.ResponseWriter, token string,
+func (client *Client) attachAccessTokenToCookie(response http.ResponseWriter, token string,
 	expiry uint64, domain *string, secure bool, path string, sameSite string) {
-	setCookie(response, accessTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
+	client.setCookie(response, accessTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
 }
 
-func attachRefreshTokenToCookie(response http.ResponseWriter, token string,
+func (client *Client) attachRefreshTokenToCookie(response http.ResponseWriter, token string,
 	expiry uint64, domain *string, secure bool, path string, sameSite string) {
-	setCookie(response, refreshTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
+	client.setCookie(response, refreshTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
 }
 
-func setIDRefreshTokenInHeaderAndCookie(response http.ResponseWriter, token string,
+func (client *Client) setIDRefreshTokenInHeaderAndCookie(response http.ResponseWriter, token string,
 	expiry uint64, domain *string, secure bool, path string, sameSite string) {
 	setHeader(response, idRefreshTokenHeaderKey, token+";"+fmt.Sprint(expiry))
 	setHeader(response, "Access-Control-Expose-Headers", idRefreshTokenHeaderKey)
 
-	setCookie(response, idRefreshTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
+	client.setCookie(response, idRefreshTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
 }
 
 func attachFrontTokenInHeaders(response http.ResponseWriter, userId string,
@@ -93,6 +102,118 @@ func saveFrontendInfoFromRequest(request *http.Request) {
 	}
 }
 
+func (client *Client) getTokenTransferMethod() string {
+	if client.config.TokenTransferMethod == "" {
+		return TokenTransferMethodCookie
+	}
+	return client.config.TokenTransferMethod
+}
+
+func (client *Client) allowsCookieTransfer() bool {
+	return client.getTokenTransferMethod() != TokenTransferMethodHeader
+}
+
+func (client *Client) allowsHeaderTransfer() bool {
+	return client.getTokenTransferMethod() != TokenTransferMethodCookie
+}
+
+// attachAccessToken sends a new access token in cookies, headers or both, depending on tokenTransferMethod
+func (client *Client) attachAccessToken(response http.ResponseWriter, accessToken core.TokenInfo, tokenTransferMethod string) {
+	if tokenTransferMethod == "" {
+		tokenTransferMethod = TokenTransferMethodCookie
+	}
+	if tokenTransferMethod != TokenTransferMethodHeader {
+		client.attachAccessTokenToCookie(
+			response,
+			accessToken.Token,
+			accessToken.Expiry,
+			accessToken.Domain,
+			accessToken.CookieSecure,
+			accessToken.CookiePath,
+			accessToken.SameSite,
+		)
+	}
+	if tokenTransferMethod != TokenTransferMethodCookie {
+		setTokenInHeaders(response, accessTokenHeaderKey, accessToken.Token)
+	}
+}
+
+// attachSessionTokens sends all the tokens of a new or refreshed session in cookies, headers or both,
+// depending on tokenTransferMethod
+func (client *Client) attachSessionTokens(response http.ResponseWriter, session core.SessionInfo, tokenTransferMethod string) {
+	accessToken := session.AccessToken
+	refreshToken := session.RefreshToken
+	idRefreshToken := session.IDRefreshToken
+
+	attachFrontTokenInHeaders(
+		response,
+		session.UserID,
+		accessToken.Expiry,
+		session.UserDataInJWT,
+	)
+
+	client.attachAccessToken(response, *accessToken, tokenTransferMethod)
+
+	if tokenTransferMethod != TokenTransferMethodHeader {
+		client.attachRefreshTokenToCookie(
+			response,
+			refreshToken.Token,
+			refreshToken.Expiry,
+			refreshToken.Domain,
+			refreshToken.CookieSecure,
+			refreshToken.CookiePath,
+			refreshToken.SameSite,
+		)
+	}
+	if tokenTransferMethod != TokenTransferMethodCookie {
+		setTokenInHeaders(response, refreshTokenHeaderKey, refreshToken.Token)
+	}
+
+	// the id refresh token tells the frontend that there is a session in cookies, so it is not needed in header mode
+	if tokenTransferMethod != TokenTransferMethodHeader {
+		client.setIDRefreshTokenInHeaderAndCookie(
+			response,
+			idRefreshToken.Token,
+			idRefreshToken.Expiry,
+			idRefreshToken.Domain,
+			idRefreshToken.CookieSecure,
+			idRefreshToken.CookiePath,
+			idRefreshToken.SameSite,
+		)
+	}
+
+	if session.AntiCsrfToken != nil {
+		setAntiCsrfTokenInHeaders(response, *session.AntiCsrfToken)
+	}
+}
+
+// setTokenInHeaders replaces any value already set, unlike setHeader, since a token cannot be a list
+func setTokenInHeaders(response http.ResponseWriter, key string, token string) {
+	response.Header().Set(key, token)
+	setHeader(response, "Access-Control-Expose-Headers", key)
+}
+
+// getAccessTokenFromAuthorizationHeader returns the token of an Authorization: Bearer header
+func getAccessTokenFromAuthorizationHeader(request *http.Request) *string {
+	value := getHeader(request, authorizationHeaderKey)
+	if value == nil {
+		return nil
+	}
+	parts := strings.SplitN(strings.TrimSpace(*value), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return nil
+	}
+	token := strings.TrimSpace(parts[1])
+	if token == "" {
+		return nil
+	}
+	return &token
+}
+
+func getRefreshTokenFromHeaders(request *http.Request) *string {
+	return getHeader(request, refreshTokenHeaderKey)
+}
+
 func getAccessTokenFromCookie(request *http.Request) *string {
 	return getCookieValue(request, accessTokenCookieKey)
 }
@@ -105,11 +226,13 @@ func getIDRefreshTokenFromCookie(request *http.Request) *string {
 	return getCookieValue(request, idRefreshTokenCookieKey)
 }
 
-func clearSessionFromCookie(response http.ResponseWriter, domain *string,
+func (client *Client) clearSessionFromCookie(response http.ResponseWriter, domain *string,
 	secure bool, accessTokenPath string, refreshTokenPath string, idRefreshTokenPath string, sameSite string) {
-	setCookie(response, accessTokenCookieKey, "", domain, secure, true, 0, accessTokenPath, sameSite)
-	setCookie(response, refreshTokenCookieKey, "", domain, secure, true, 0, refreshTokenPath, sameSite)
-	setCookie(response, idRefreshTokenCookieKey, "", domain, secure, true, 0, idRefreshTokenPath, sameSite)
+	client.instance.GetLogger().Debug("clearing the session cookies", "accessTokenPath", accessTokenPath,
+		"refreshTokenPath", refreshTokenPath)
+	client.setCookie(response, accessTokenCookieKey, "", domain, secure, true, 0, accessTokenPath, sameSite)
+	client.setCookie(response, refreshTokenCookieKey, "", domain, secure, true, 0, refreshTokenPath, sameSite)
+	client.setCookie(response, idRefreshTokenCookieKey, "", domain, secure, true, 0, idRefreshTokenPath, sameSite)
 	setHeader(response, idRefreshTokenHeaderKey, "remove")
 	setHeader(response, "Access-Control-Expose-Headers", idRefreshTokenHeaderKey)
 }
@@ -118,29 +241,28 @@ func getRefreshTokenFromCookie(request *http.Request) *string {
 	return getCookieValue(request, refreshTokenCookieKey)
 }
 
-func setCookie(response http.ResponseWriter, name string, value string,
+func (client *Client) setCookie(response http.ResponseWriter, name string, value string,
 	domain *string, secure bool, httpOnly bool, expires uint64, path string, sameSite string) {
 
-	if configMap != nil {
-		if configMap.CookieDomain != "" {
-			domain = &configMap.CookieDomain
-		}
-		if configMap.CookieSecure != nil {
-			secure = *configMap.CookieSecure
-		}
-		if configMap.CookieSameSite == "none" || configMap.CookieSameSite == "lax" ||
-			configMap.CookieSameSite == "strict" {
-			sameSite = configMap.CookieSameSite
-		}
-		if name == accessTokenCookieKey && configMap.AccessTokenPath != "" {
-			path = configMap.AccessTokenPath
-		}
-		if name == idRefreshTokenCookieKey && configMap.AccessTokenPath != "" {
-			path = configMap.AccessTokenPath
-		}
-		if name == refreshTokenCookieKey && configMap.RefreshAPIPath != "" {
-			path = configMap.RefreshAPIPath
-		}
+	config := client.config
+	if config.CookieDomain != "" {
+		domain = &config.CookieDomain
+	}
+	if config.CookieSecure != nil {
+		secure = *config.CookieSecure
+	}
+	if config.CookieSameSite == "none" || config.CookieSameSite == "lax" ||
+		config.CookieSameSite == "strict" {
+		sameSite = config.CookieSameSite
+	}
+	if name == accessTokenCookieKey && config.AccessTokenPath != "" {
+		path = config.AccessTokenPath
+	}
+	if name == idRefreshTokenCookieKey && config.AccessTokenPath != "" {
+		path = config.AccessTokenPath
+	}
+	if name == refreshTokenCookieKey && config.RefreshAPIPath != "" {
+		path = config.RefreshAPIPath
 	}
 
 	var sameSiteField = http.SameSiteNoneMode
@@ -229,17 +351,34 @@ func setCookieValue(w http.ResponseWriter, cookie *http.Cookie) {
 	}
 }
 
-func setRelevantHeadersForOptionsAPI(response http.ResponseWriter) {
-	setHeader(response, "Access-Control-Allow-Headers", antiCsrfHeaderKey)
-	setHeader(response, "Access-Control-Allow-Headers", frontendSDKNameHeaderKey)
-	setHeader(response, "Access-Control-Allow-Headers", frontendSDKVersionHeaderKey)
+func (client *Client) setRelevantHeadersForOptionsAPI(response http.ResponseWriter) {
+	for _, header := range client.getCORSAllowedHeaders() {
+		setHeader(response, "Access-Control-Allow-Headers", header)
+	}
 	setHeader(response, "Access-Control-Allow-Credentials", "true")
 }
 
-func getCORSAllowedHeaders() []string {
-	return []string{
+// getCORSAllowedHeaders returns the request headers the frontend sends. Tokens sent in headers are
+// only read if the token transfer method allows it
+func (client *Client) getCORSAllowedHeaders() []string {
+	headers := []string{
 		antiCsrfHeaderKey, frontendSDKNameHeaderKey, frontendSDKVersionHeaderKey,
 	}
+	if client.allowsHeaderTransfer() {
+		headers = append(headers, authorizationHeaderKey, refreshTokenHeaderKey)
+	}
+	return headers
+}
+
+// getCORSExposedHeaders returns the response headers the frontend reads
+func (client *Client) getCORSExposedHeaders() []string {
+	headers := []string{
+		frontTokenHeaderKey, idRefreshTokenHeaderKey, antiCsrfHeaderKey,
+	}
+	if client.allowsHeaderTransfer() {
+		headers = append(headers, accessTokenHeaderKey, refreshTokenHeaderKey)
+	}
+	return headers
 }
 
 func getCookieName(cookie string) string {
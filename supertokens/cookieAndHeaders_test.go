@@ -7,11 +7,13 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/core"
 )
 
 func Test_setCookie_Once(t *testing.T) {
 	w := httptest.NewRecorder()
-	setCookie(w, "abc", "someVal", nil, false,
+	client := &Client{}
+	client.setCookie(w, "abc", "someVal", nil, false,
 		false, 0, "/cookie", "lax")
 	cookieMap := getCookieNameValuesMap(w)
 	assert.Equal(t, "someVal", cookieMap["abc"])
@@ -19,12 +21,13 @@ func Test_setCookie_Once(t *testing.T) {
 
 func Test_setCookie_Replace(t *testing.T) {
 	w := httptest.NewRecorder()
-	setCookie(w, "abc", "someVal", nil, false,
+	client := &Client{}
+	client.setCookie(w, "abc", "someVal", nil, false,
 		false, 0, "/cookie", "lax")
 	cookieMap := getCookieNameValuesMap(w)
 	assert.Equal(t, "someVal", cookieMap["abc"])
 
-	setCookie(w, "abc", "someOtherVal", nil, false,
+	client.setCookie(w, "abc", "someOtherVal", nil, false,
 		false, 0, "/cookie", "lax")
 	cookieMap = getCookieNameValuesMap(w)
 	assert.Equal(t, "someOtherVal", cookieMap["abc"])
@@ -32,18 +35,19 @@ func Test_setCookie_Replace(t *testing.T) {
 
 func Test_setCookie_Multiple(t *testing.T) {
 	w := httptest.NewRecorder()
-	setCookie(w, "abc", "valOne", nil, false,
+	client := &Client{}
+	client.setCookie(w, "abc", "valOne", nil, false,
 		false, 0, "/cookie", "lax")
 	cookieMap := getCookieNameValuesMap(w)
 	assert.Equal(t, "valOne", cookieMap["abc"])
 
-	setCookie(w, "xyz", "valOne", nil, false,
+	client.setCookie(w, "xyz", "valOne", nil, false,
 		false, 0, "/cookie", "lax")
 	cookieMap = getCookieNameValuesMap(w)
 	assert.Equal(t, "valOne", cookieMap["abc"])
 	assert.Equal(t, "valOne", cookieMap["xyz"])
 
-	setCookie(w, "abc", "valTwo", nil, false,
+	client.setCookie(w, "abc", "valTwo", nil, false,
 		false, 0, "/cookie", "lax")
 	cookieMap = getCookieNameValuesMap(w)
 	assert.Equal(t, "valTwo", cookieMap["abc"])
@@ -60,3 +64,68 @@ func getCookieNameValuesMap(w http.ResponseWriter) map[string]string {
 	}
 	return cookies
 }
+
+func Test_getAccessTokenFromAuthorizationHeader(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	assert.Nil(t, getAccessTokenFromAuthorizationHeader(request))
+
+	request.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	assert.Nil(t, getAccessTokenFromAuthorizationHeader(request))
+
+	request.Header.Set("Authorization", "Bearer ")
+	assert.Nil(t, getAccessTokenFromAuthorizationHeader(request))
+
+	request.Header.Set("Authorization", "bearer someToken")
+	assert.Equal(t, "someToken", *getAccessTokenFromAuthorizationHeader(request))
+}
+
+func Test_attachSessionTokens_TransferMethods(t *testing.T) {
+	token := core.TokenInfo{Token: "token", Expiry: 1, CookiePath: "/"}
+	session := core.SessionInfo{
+		AccessToken:    &core.TokenInfo{Token: "accessToken", Expiry: 1, CookiePath: "/"},
+		RefreshToken:   &core.TokenInfo{Token: "refreshToken", Expiry: 1, CookiePath: "/refresh"},
+		IDRefreshToken: &token,
+	}
+	client := &Client{}
+
+	w := httptest.NewRecorder()
+	client.attachSessionTokens(w, session, TokenTransferMethodHeader)
+	cookieMap := getCookieNameValuesMap(w)
+	assert.Equal(t, "accessToken", w.Header().Get(accessTokenHeaderKey))
+	assert.Equal(t, "refreshToken", w.Header().Get(refreshTokenHeaderKey))
+	assert.Empty(t, cookieMap[accessTokenCookieKey])
+	assert.Empty(t, cookieMap[refreshTokenCookieKey])
+	assert.Empty(t, cookieMap[idRefreshTokenCookieKey])
+
+	w = httptest.NewRecorder()
+	client.attachSessionTokens(w, session, TokenTransferMethodCookie)
+	cookieMap = getCookieNameValuesMap(w)
+	assert.Empty(t, w.Header().Get(accessTokenHeaderKey))
+	assert.Equal(t, "accessToken", cookieMap[accessTokenCookieKey])
+	assert.Equal(t, "refreshToken", cookieMap[refreshTokenCookieKey])
+	assert.Equal(t, "token", cookieMap[idRefreshTokenCookieKey])
+
+	w = httptest.NewRecorder()
+	client.attachSessionTokens(w, session, TokenTransferMethodAny)
+	cookieMap = getCookieNameValuesMap(w)
+	assert.Equal(t, "accessToken", w.Header().Get(accessTokenHeaderKey))
+	assert.Equal(t, "accessToken", cookieMap[accessTokenCookieKey])
+}
+
+func Test_CORSHeaders_TransferMethods(t *testing.T) {
+	client := &Client{}
+	assert.NotContains(t, client.GetCORSAllowedHeaders(), authorizationHeaderKey)
+	assert.NotContains(t, client.GetCORSExposedHeaders(), accessTokenHeaderKey)
+
+	for _, transferMethod := range []string{TokenTransferMethodHeader, TokenTransferMethodAny} {
+		client := &Client{config: ConfigMap{TokenTransferMethod: transferMethod}}
+		assert.Subset(t, client.GetCORSAllowedHeaders(), []string{authorizationHeaderKey, refreshTokenHeaderKey})
+		assert.Subset(t, client.GetCORSExposedHeaders(), []string{accessTokenHeaderKey, refreshTokenHeaderKey})
+
+		w := httptest.NewRecorder()
+		client.SetRelevantHeadersForOptionsAPI(w)
+		allowed := strings.ToLower(w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Contains(t, allowed, authorizationHeaderKey)
+		assert.Contains(t, allowed, refreshTokenHeaderKey)
+	}
+}
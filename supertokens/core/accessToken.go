@@ -17,6 +17,10 @@
 package core
 
 import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"strings"
+
 	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
@@ -31,60 +35,130 @@ type accessTokenInfoStruct struct {
 	timeCreated             uint64
 }
 
-func getInfoFromAccessToken(token string, jwtSigningPublicKey string, doAntiCsrfCheck bool) (accessTokenInfoStruct, error) {
-	payload, verifyError := verifyJWTAndGetPayload(token, jwtSigningPublicKey)
+// AccessTokenMetadata is what an access token says about itself. Times are in milliseconds since epoch
+type AccessTokenMetadata struct {
+	SessionHandle string
+	UserID        string
+	Expiry        uint64
+	TimeCreated   uint64
+	// AntiCsrfToken is nil if anti-csrf is disabled in the core
+	AntiCsrfToken *string
+	// ParentRefreshTokenHash1 is set if the token was issued by a refresh that the core has not yet confirmed
+	ParentRefreshTokenHash1 *string
+}
+
+// getInfoFromAccessToken verifies the token against each of the given keys in order, so that tokens signed
+// with a key that was recently rotated out are still accepted
+func getInfoFromAccessToken(token string, jwtSigningPublicKeys []string, doAntiCsrfCheck bool) (accessTokenInfoStruct, error) {
+	var payload map[string]interface{}
+	var verifyError error
+	for i, jwtSigningPublicKey := range jwtSigningPublicKeys {
+		var err error
+		payload, err = verifyJWTAndGetPayload(token, jwtSigningPublicKey)
+		if err == nil {
+			verifyError = nil
+			break
+		}
+		if i == 0 {
+			verifyError = err
+		}
+	}
 	if verifyError != nil {
 		return accessTokenInfoStruct{}, errors.TryRefreshTokenError{
-			Msg: verifyError.Error(),
+			Msg:         verifyError.Error(),
+			ActualError: verifyError,
+			Reason:      errors.ReasonAccessTokenInvalid,
 		}
 	}
 
-	var sessionHandle *string = nil
-	if payload["sessionHandle"] != nil {
-		temp := payload["sessionHandle"].(string)
-		sessionHandle = &temp
+	accessTokenInfo, err := getInfoFromAccessTokenPayload(payload, doAntiCsrfCheck)
+	if err != nil {
+		return accessTokenInfoStruct{}, err
 	}
 
-	var userID *string = nil
-	if payload["userId"] != nil {
-		temp := payload["userId"].(string)
-		userID = &temp
+	if accessTokenInfo.expiryTime < getCurrTimeInMS() {
+		return accessTokenInfoStruct{}, errors.TryRefreshTokenError{
+			Msg:    "Access token expired",
+			Reason: errors.ReasonAccessTokenExpired,
+		}
 	}
+	return accessTokenInfo, nil
+}
 
-	var refreshTokenHash1 *string = nil
-	if payload["refreshTokenHash1"] != nil {
-		temp := payload["refreshTokenHash1"].(string)
-		refreshTokenHash1 = &temp
+// GetAccessTokenMetadata reads the metadata of an access token without verifying its signature or expiry, so it must
+// not be used to trust a token. A malformed token returns an error
+func GetAccessTokenMetadata(token string) (AccessTokenMetadata, error) {
+	var splitted = strings.Split(token, ".")
+	if len(splitted) != 3 {
+		return AccessTokenMetadata{}, errors.GeneralError{
+			Msg: "Invalid JWT",
+		}
 	}
-
-	var parentRefreshTokenHash1 *string = nil
-	if payload["parentRefreshTokenHash1"] != nil {
-		temp := payload["parentRefreshTokenHash1"].(string)
-		parentRefreshTokenHash1 = &temp
+	decodedPayload, err := b64.StdEncoding.DecodeString(splitted[1])
+	if err != nil {
+		return AccessTokenMetadata{}, errors.GeneralError{
+			Msg:         err.Error(),
+			ActualError: err,
+		}
 	}
-
-	var userData *map[string]interface{} = nil
-	if payload["userData"] != nil {
-		temp := payload["userData"].(map[string]interface{})
-		userData = &temp
+	var payload map[string]interface{}
+	if err := json.Unmarshal(decodedPayload, &payload); err != nil {
+		return AccessTokenMetadata{}, errors.GeneralError{
+			Msg:         err.Error(),
+			ActualError: err,
+		}
 	}
-
-	var antiCsrfToken *string = nil
-	if payload["antiCsrfToken"] != nil {
-		temp := payload["antiCsrfToken"].(string)
-		antiCsrfToken = &temp
+	accessTokenInfo, err := getInfoFromAccessTokenPayload(payload, false)
+	if err != nil {
+		return AccessTokenMetadata{}, err
 	}
+	return AccessTokenMetadata{
+		SessionHandle:           accessTokenInfo.sessionHandle,
+		UserID:                  accessTokenInfo.userID,
+		Expiry:                  accessTokenInfo.expiryTime,
+		TimeCreated:             accessTokenInfo.timeCreated,
+		AntiCsrfToken:           accessTokenInfo.antiCsrfToken,
+		ParentRefreshTokenHash1: accessTokenInfo.parentRefreshTokenHash1,
+	}, nil
+}
 
-	var expiryTime *uint64 = nil
-	if payload["expiryTime"] != nil {
-		temp := uint64(payload["expiryTime"].(float64))
-		expiryTime = &temp
+func getInfoFromAccessTokenPayload(payload map[string]interface{}, doAntiCsrfCheck bool) (accessTokenInfoStruct, error) {
+	sessionHandle, err := getStringFromAccessTokenPayload(payload, "sessionHandle")
+	if err != nil {
+		return accessTokenInfoStruct{}, err
+	}
+	userID, err := getStringFromAccessTokenPayload(payload, "userId")
+	if err != nil {
+		return accessTokenInfoStruct{}, err
+	}
+	refreshTokenHash1, err := getStringFromAccessTokenPayload(payload, "refreshTokenHash1")
+	if err != nil {
+		return accessTokenInfoStruct{}, err
+	}
+	parentRefreshTokenHash1, err := getStringFromAccessTokenPayload(payload, "parentRefreshTokenHash1")
+	if err != nil {
+		return accessTokenInfoStruct{}, err
+	}
+	antiCsrfToken, err := getStringFromAccessTokenPayload(payload, "antiCsrfToken")
+	if err != nil {
+		return accessTokenInfoStruct{}, err
+	}
+	expiryTime, err := getTimeFromAccessTokenPayload(payload, "expiryTime")
+	if err != nil {
+		return accessTokenInfoStruct{}, err
+	}
+	timeCreated, err := getTimeFromAccessTokenPayload(payload, "timeCreated")
+	if err != nil {
+		return accessTokenInfoStruct{}, err
 	}
 
-	var timeCreated *uint64 = nil
-	if payload["timeCreated"] != nil {
-		temp := uint64(payload["timeCreated"].(float64))
-		timeCreated = &temp
+	var userData *map[string]interface{} = nil
+	if payload["userData"] != nil {
+		temp, ok := payload["userData"].(map[string]interface{})
+		if !ok {
+			return accessTokenInfoStruct{}, getInvalidAccessTokenFieldError("userData")
+		}
+		userData = &temp
 	}
 
 	if sessionHandle == nil ||
@@ -95,13 +169,8 @@ func getInfoFromAccessToken(token string, jwtSigningPublicKey string, doAntiCsrf
 		expiryTime == nil ||
 		timeCreated == nil {
 		return accessTokenInfoStruct{}, errors.TryRefreshTokenError{
-			Msg: "Access token does not contain all the information. Maybe the structure has changed?",
-		}
-	}
-
-	if *expiryTime < getCurrTimeInMS() {
-		return accessTokenInfoStruct{}, errors.TryRefreshTokenError{
-			Msg: "Access token expired",
+			Msg:    "Access token does not contain all the information. Maybe the structure has changed?",
+			Reason: errors.ReasonAccessTokenInvalid,
 		}
 	}
 
@@ -116,3 +185,35 @@ func getInfoFromAccessToken(token string, jwtSigningPublicKey string, doAntiCsrf
 		timeCreated:             *timeCreated,
 	}, nil
 }
+
+// getStringFromAccessTokenPayload returns nil if the field is missing, and an error if it is not a string
+func getStringFromAccessTokenPayload(payload map[string]interface{}, key string) (*string, error) {
+	if payload[key] == nil {
+		return nil, nil
+	}
+	value, ok := payload[key].(string)
+	if !ok {
+		return nil, getInvalidAccessTokenFieldError(key)
+	}
+	return &value, nil
+}
+
+// getTimeFromAccessTokenPayload returns nil if the field is missing, and an error if it is not a number
+func getTimeFromAccessTokenPayload(payload map[string]interface{}, key string) (*uint64, error) {
+	if payload[key] == nil {
+		return nil, nil
+	}
+	value, ok := payload[key].(float64)
+	if !ok {
+		return nil, getInvalidAccessTokenFieldError(key)
+	}
+	result := uint64(value)
+	return &result, nil
+}
+
+func getInvalidAccessTokenFieldError(key string) error {
+	return errors.TryRefreshTokenError{
+		Msg:    "Access token field " + key + " has the wrong type. Maybe the structure has changed?",
+		Reason: errors.ReasonAccessTokenInvalid,
+	}
+}
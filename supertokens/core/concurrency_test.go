@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	b64 "encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// run with go test -race to catch unsynchronised access to the querier and handshake info
+
+type testCore struct {
+	server      *httptest.Server
+	privateKey  *rsa.PrivateKey
+	publicKey   string
+	verifyCalls int
+	lock        sync.Mutex
+}
+
+func newTestCore(t *testing.T) *testCore {
+	c := &testCore{}
+	c.rotateKey(t)
+	c.server = httptest.NewServer(http.HandlerFunc(c.handle))
+	return c
+}
+
+// rotateKey makes the core sign tokens with a new key pair
+func (c *testCore) rotateKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.privateKey = privateKey
+	c.publicKey = b64.StdEncoding.EncodeToString(der)
+}
+
+func (c *testCore) getPublicKey() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.publicKey
+}
+
+func (c *testCore) getVerifyCalls() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.verifyCalls
+}
+
+func (c *testCore) createAccessToken(t *testing.T, payload map[string]interface{}) string {
+	c.lock.Lock()
+	privateKey := c.privateKey
+	c.lock.Unlock()
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedPayload := b64.StdEncoding.EncodeToString(jsonPayload)
+	digest := sha256.Sum256([]byte(header + "." + encodedPayload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header + "." + encodedPayload + "." + b64.StdEncoding.EncodeToString(signature)
+}
+
+func (c *testCore) handle(w http.ResponseWriter, r *http.Request) {
+	expiry := getCurrTimeInMS() + 3600000
+	session := map[string]interface{}{
+		"handle":        "handle",
+		"userId":        "userId",
+		"userDataInJWT": map[string]interface{}{},
+	}
+	token := map[string]interface{}{
+		"token":        "token",
+		"expiry":       expiry,
+		"createdTime":  getCurrTimeInMS(),
+		"cookiePath":   "/",
+		"cookieSecure": false,
+		"sameSite":     "lax",
+	}
+	publicKey := c.getPublicKey()
+	var response map[string]interface{}
+	switch r.URL.Path {
+	case "/apiversion":
+		response = map[string]interface{}{"versions": []string{"2.3"}}
+	case "/handshake":
+		response = map[string]interface{}{
+			"jwtSigningPublicKey":            publicKey,
+			"jwtSigningPublicKeyExpiryTime":  expiry,
+			"cookieSecure":                   false,
+			"accessTokenPath":                "/",
+			"refreshTokenPath":               "/refresh",
+			"enableAntiCsrf":                 false,
+			"accessTokenBlacklistingEnabled": false,
+			"cookieSameSite":                 "lax",
+			"idRefreshTokenPath":             "/",
+			"sessionExpiredStatusCode":       401,
+		}
+	case "/session/verify":
+		c.lock.Lock()
+		c.verifyCalls++
+		c.lock.Unlock()
+		response = map[string]interface{}{
+			"status":                        "OK",
+			"session":                       session,
+			"jwtSigningPublicKey":           publicKey,
+			"jwtSigningPublicKeyExpiryTime": expiry,
+		}
+	case "/session/refresh":
+		response = map[string]interface{}{
+			"status":         "OK",
+			"session":        session,
+			"accessToken":    token,
+			"refreshToken":   token,
+			"idRefreshToken": token,
+		}
+	default:
+		w.WriteHeader(404)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func TestConcurrentGetAndRefreshSession(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+
+	payload := map[string]interface{}{
+		"sessionHandle":     "handle",
+		"userId":            "userId",
+		"refreshTokenHash1": "hash",
+		"userData":          map[string]interface{}{},
+		"expiryTime":        getCurrTimeInMS() + 3600000,
+		"timeCreated":       getCurrTimeInMS(),
+	}
+	localToken := c.createAccessToken(t, payload)
+	payload["parentRefreshTokenHash1"] = "parentHash"
+	coreToken := c.createAccessToken(t, payload)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 300)
+	for i := 0; i < 100; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if _, err := instance.GetSession(context.Background(), localToken, nil, false); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := instance.GetSession(context.Background(), coreToken, nil, false); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := instance.RefreshSession(context.Background(), "refreshToken", nil); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -17,7 +17,7 @@
 package core
 
 // VERSION current version of the lib
-const VERSION = "1.4.2"
+const VERSION = "1.5.0"
 
 // CdiVersion core driver interface version supported
 var CdiVersion = []string{"2.0", "2.1", "2.2", "2.3"}
@@ -59,6 +59,8 @@ func (info *deviceInfo) AddToFrontendSDKs(name string, version string) {
 
 // GetFrontendSDKs get info about devices that have queried
 func (info *deviceInfo) GetFrontendSDKs() []map[string]string {
+	deviceInfoLock.Lock()
+	defer deviceInfoLock.Unlock()
 	result := []map[string]string{}
 	for i := 0; i < len(info.frontendSDK); i++ {
 		result = append(result, map[string]string{
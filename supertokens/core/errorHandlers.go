@@ -17,71 +17,120 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
 	"net/http"
 	"sync"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
 type errorHandlers struct {
-	OnTokenTheftDetectedErrorHandler func(sessionHandle string, userID string, response http.ResponseWriter)
-	OnUnauthorizedErrorHandler       func(error, http.ResponseWriter)
-	OnTryRefreshTokenErrorHandler    func(error, http.ResponseWriter)
-	OnGeneralErrorHandler            func(error, http.ResponseWriter)
+	OnTokenTheftDetectedErrorHandler  func(sessionHandle string, userID string, response http.ResponseWriter)
+	OnUnauthorizedErrorHandler        func(error, http.ResponseWriter)
+	OnTryRefreshTokenErrorHandler     func(error, http.ResponseWriter)
+	OnGeneralErrorHandler             func(error, http.ResponseWriter)
+	OnOfflineVerificationErrorHandler func(error, http.ResponseWriter)
+	OnInvalidClaimErrorHandler        func(error, http.ResponseWriter)
 }
 
-func defaultTokenTheftDetectedErrorHandler(sessionHandle string, userID string, w http.ResponseWriter) {
-	handshakeInfo, handshakeInfoError := GetHandshakeInfoInstance()
+func (instance *Instance) defaultTokenTheftDetectedErrorHandler(sessionHandle string, userID string, w http.ResponseWriter) {
+	handshakeInfo, handshakeInfoError := instance.GetHandshakeInfo(context.Background())
 	if handshakeInfoError != nil {
-		GetErrorHandlersInstance().OnGeneralErrorHandler(handshakeInfoError, w)
+		instance.GetErrorHandlers().OnGeneralErrorHandler(handshakeInfoError, w)
 		return
 	}
 	w.WriteHeader(handshakeInfo.SessionExpiredStatusCode)
 	w.Write([]byte("token theft detected"))
-	_, _ = RevokeSession(sessionHandle)
+	_, _ = instance.RevokeSession(context.Background(), sessionHandle)
 }
 
-func defaultUnauthorizedErrorHandler(err error, w http.ResponseWriter) {
-	handshakeInfo, handshakeInfoError := GetHandshakeInfoInstance()
+func (instance *Instance) defaultUnauthorizedErrorHandler(err error, w http.ResponseWriter) {
+	sessionExpiredStatusCode, handshakeInfoError := instance.getSessionExpiredStatusCode()
 	if handshakeInfoError != nil {
-		GetErrorHandlersInstance().OnGeneralErrorHandler(handshakeInfoError, w)
+		instance.GetErrorHandlers().OnGeneralErrorHandler(handshakeInfoError, w)
 		return
 	}
-	w.WriteHeader(handshakeInfo.SessionExpiredStatusCode)
+	w.WriteHeader(sessionExpiredStatusCode)
 	w.Write([]byte("Unauthorized: " + err.Error()))
 }
 
-func defaultTryRefreshTokenErrorHandler(err error, w http.ResponseWriter) {
-	handshakeInfo, handshakeInfoError := GetHandshakeInfoInstance()
+func (instance *Instance) defaultTryRefreshTokenErrorHandler(err error, w http.ResponseWriter) {
+	sessionExpiredStatusCode, handshakeInfoError := instance.getSessionExpiredStatusCode()
 	if handshakeInfoError != nil {
-		GetErrorHandlersInstance().OnGeneralErrorHandler(handshakeInfoError, w)
+		instance.GetErrorHandlers().OnGeneralErrorHandler(handshakeInfoError, w)
 		return
 	}
-	w.WriteHeader(handshakeInfo.SessionExpiredStatusCode)
+	w.WriteHeader(sessionExpiredStatusCode)
 	w.Write([]byte("try refresh token: " + err.Error()))
 }
 
+// defaultOfflineVerificationErrorHandler answers like an unauthorised session, since the pinned key rejected the token.
+// It does not use the handshake info since the core may not be reachable
+func defaultOfflineVerificationErrorHandler(err error, w http.ResponseWriter) {
+	w.WriteHeader(defaultSessionExpiredStatusCode)
+	w.Write([]byte("Unauthorized: " + err.Error()))
+}
+
+// defaultSessionExpiredStatusCode is the status code the cores use unless configured otherwise
+const defaultSessionExpiredStatusCode = 401
+
+// getSessionExpiredStatusCode returns the status code the frontend expects when a session has expired. With offline
+// verification the core may not be reachable, so the default is used instead of querying the handshake info
+func (instance *Instance) getSessionExpiredStatusCode() (int, error) {
+	if instance.IsOfflineVerificationEnabled() {
+		return defaultSessionExpiredStatusCode, nil
+	}
+	handshakeInfo, err := instance.GetHandshakeInfo(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	return handshakeInfo.SessionExpiredStatusCode, nil
+}
+
+// defaultInvalidClaimErrorHandler responds with a 403 and the claims that failed validation, since the session itself is valid
+func defaultInvalidClaimErrorHandler(err error, w http.ResponseWriter) {
+	claimValidationErrors := []errors.ClaimValidationError{}
+	var invalidClaimError errors.InvalidClaimError
+	if goerrors.As(err, &invalidClaimError) && invalidClaimError.ClaimValidationErrors != nil {
+		claimValidationErrors = invalidClaimError.ClaimValidationErrors
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":               "invalid claim",
+		"claimValidationErrors": claimValidationErrors,
+	})
+}
+
 func defaultGeneralErrorHandler(err error, w http.ResponseWriter) {
 	w.WriteHeader(500)
 	w.Write([]byte("Internal error: " + err.Error()))
 }
 
-var errorHandlerInstantiated *errorHandlers
-var errorHandlersOnce *sync.Once = new(sync.Once)
-
 // GetErrorHandlersInstance returns all the error handlers.
 func GetErrorHandlersInstance() *errorHandlers {
-	errorHandlersOnce.Do(func() {
-		errorHandlerInstantiated = &errorHandlers{
-			OnTokenTheftDetectedErrorHandler: defaultTokenTheftDetectedErrorHandler,
-			OnUnauthorizedErrorHandler:       defaultUnauthorizedErrorHandler,
-			OnTryRefreshTokenErrorHandler:    defaultTryRefreshTokenErrorHandler,
-			OnGeneralErrorHandler:            defaultGeneralErrorHandler,
+	return defaultInstance.GetErrorHandlers()
+}
+
+// GetErrorHandlers returns all the error handlers of this instance.
+func (instance *Instance) GetErrorHandlers() *errorHandlers {
+	instance.errorHandlersOnce.Do(func() {
+		instance.errorHandlers = &errorHandlers{
+			OnTokenTheftDetectedErrorHandler:  instance.defaultTokenTheftDetectedErrorHandler,
+			OnUnauthorizedErrorHandler:        instance.defaultUnauthorizedErrorHandler,
+			OnTryRefreshTokenErrorHandler:     instance.defaultTryRefreshTokenErrorHandler,
+			OnGeneralErrorHandler:             defaultGeneralErrorHandler,
+			OnOfflineVerificationErrorHandler: defaultOfflineVerificationErrorHandler,
+			OnInvalidClaimErrorHandler:        defaultInvalidClaimErrorHandler,
 		}
 	})
-	return errorHandlerInstantiated
+	return instance.errorHandlers
 }
 
 // ResetError to be used for testing only
 func ResetError() {
-	errorHandlerInstantiated = nil
-	errorHandlersOnce = new(sync.Once)
+	defaultInstance.errorHandlers = nil
+	defaultInstance.errorHandlersOnce = new(sync.Once)
 }
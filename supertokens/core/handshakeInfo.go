@@ -17,7 +17,8 @@
 package core
 
 import (
-	"sync"
+	"context"
+	"time"
 )
 
 type handshakeInfo struct {
@@ -32,53 +33,139 @@ type handshakeInfo struct {
 	CookieSameSite                 string
 	IDRefreshTokenPath             string
 	SessionExpiredStatusCode       int
+	instance                       *Instance
+	previousJwtSigningPublicKeys   []previousJwtSigningPublicKey
 }
 
-var handshakeInfoInstantiated *handshakeInfo
-
 // GetHandshakeInfoInstance returns handshake info.
 func GetHandshakeInfoInstance() (*handshakeInfo, error) {
-	if handshakeInfoInstantiated == nil {
-		handshakeInfoLock.Lock()
-		defer handshakeInfoLock.Unlock()
-		if handshakeInfoInstantiated == nil {
-			response, err := GetQuerierInstance().SendPostRequest("handshake", "/handshake", map[string]interface{}{})
-			if err != nil {
-				return nil, err
-			}
-			var domain *string = nil
-			if response["cookieDomain"] != nil {
-				temp := response["cookieDomain"].(string)
-				domain = &temp
-			}
-			handshakeInfoInstantiated = &handshakeInfo{
-				JwtSigningPublicKey:            response["jwtSigningPublicKey"].(string),
-				CookieDomain:                   domain,
-				CookieSecure:                   response["cookieSecure"].(bool),
-				AccessTokenPath:                response["accessTokenPath"].(string),
-				RefreshTokenPath:               response["refreshTokenPath"].(string),
-				EnableAntiCsrf:                 response["enableAntiCsrf"].(bool),
-				AccessTokenBlacklistingEnabled: response["accessTokenBlacklistingEnabled"].(bool),
-				JwtSigningPublicKeyExpiryTime:  uint64(response["jwtSigningPublicKeyExpiryTime"].(float64)),
-				CookieSameSite:                 response["cookieSameSite"].(string),
-				IDRefreshTokenPath:             response["idRefreshTokenPath"].(string),
-				SessionExpiredStatusCode:       int(response["sessionExpiredStatusCode"].(float64)),
-			}
+	return defaultInstance.GetHandshakeInfo(context.Background())
+}
+
+// GetHandshakeInfoInstanceWithContext returns handshake info, aborting the handshake when ctx is done.
+func GetHandshakeInfoInstanceWithContext(ctx context.Context) (*handshakeInfo, error) {
+	return defaultInstance.GetHandshakeInfo(ctx)
+}
+
+// GetHandshakeInfo returns handshake info of this instance. Concurrent callers share one handshake with the core
+func (instance *Instance) GetHandshakeInfo(ctx context.Context) (*handshakeInfo, error) {
+	instance.handshakeInfoLock.RLock()
+	info := instance.handshakeInfo
+	instance.handshakeInfoLock.RUnlock()
+	if info != nil {
+		return info, nil
+	}
+	result, err := instance.handshakeInfoCall.do(ctx, func(ctx context.Context) (interface{}, error) {
+		instance.handshakeInfoLock.RLock()
+		info := instance.handshakeInfo
+		instance.handshakeInfoLock.RUnlock()
+		if info != nil {
+			return info, nil
+		}
+		response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "handshake", "/handshake", map[string]interface{}{})
+		if err != nil {
+			instance.GetLogger().Error("handshake: could not get the handshake info from the core", "error", err)
+			return nil, err
+		}
+		var domain *string = nil
+		if response["cookieDomain"] != nil {
+			temp := response["cookieDomain"].(string)
+			domain = &temp
 		}
+		info = &handshakeInfo{
+			JwtSigningPublicKey:            response["jwtSigningPublicKey"].(string),
+			CookieDomain:                   domain,
+			CookieSecure:                   response["cookieSecure"].(bool),
+			AccessTokenPath:                response["accessTokenPath"].(string),
+			RefreshTokenPath:               response["refreshTokenPath"].(string),
+			EnableAntiCsrf:                 response["enableAntiCsrf"].(bool),
+			AccessTokenBlacklistingEnabled: response["accessTokenBlacklistingEnabled"].(bool),
+			JwtSigningPublicKeyExpiryTime:  uint64(response["jwtSigningPublicKeyExpiryTime"].(float64)),
+			CookieSameSite:                 response["cookieSameSite"].(string),
+			IDRefreshTokenPath:             response["idRefreshTokenPath"].(string),
+			SessionExpiredStatusCode:       int(response["sessionExpiredStatusCode"].(float64)),
+			instance:                       instance,
+		}
+		instance.handshakeInfoLock.Lock()
+		if instance.handshakeInfo == nil {
+			instance.handshakeInfo = info
+		}
+		info = instance.handshakeInfo
+		instance.handshakeInfoLock.Unlock()
+		instance.startSigningKeyRefresher()
+		return info, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return handshakeInfoInstantiated, nil
+	return result.(*handshakeInfo), nil
 }
 
-var handshakeInfoLock sync.Mutex
-
+// UpdateJwtSigningPublicKeyInfo caches a copy of the handshake info with the new signing key.
+// Cached handshake info is never modified, so callers can read it without locking.
+// If the key changed, the previous one is still accepted for the configured grace period
+// and the rotation handler is called
 func (info *handshakeInfo) UpdateJwtSigningPublicKeyInfo(newKey string, newExpiry uint64) {
-	handshakeInfoLock.Lock()
-	defer handshakeInfoLock.Unlock()
-	info.JwtSigningPublicKey = newKey
-	info.JwtSigningPublicKeyExpiryTime = newExpiry
+	instance := info.instance
+	instance.handshakeInfoLock.Lock()
+	if instance.handshakeInfo == nil {
+		instance.handshakeInfoLock.Unlock()
+		return
+	}
+	current := instance.handshakeInfo
+	updated := *current
+	updated.JwtSigningPublicKey = newKey
+	updated.JwtSigningPublicKeyExpiryTime = newExpiry
+	var rotation *SigningKeyRotation = nil
+	if current.JwtSigningPublicKey != newKey {
+		options := instance.getSigningKeyOptions()
+		now := getCurrTimeInMS()
+		updated.previousJwtSigningPublicKeys = []previousJwtSigningPublicKey{}
+		for _, previous := range current.previousJwtSigningPublicKeys {
+			if previous.validUntil > now && previous.key != newKey {
+				updated.previousJwtSigningPublicKeys = append(updated.previousJwtSigningPublicKeys, previous)
+			}
+		}
+		if options.GracePeriod > 0 {
+			updated.previousJwtSigningPublicKeys = append(updated.previousJwtSigningPublicKeys, previousJwtSigningPublicKey{
+				key:        current.JwtSigningPublicKey,
+				validUntil: now + uint64(options.GracePeriod/time.Millisecond),
+			})
+		}
+		rotation = &SigningKeyRotation{
+			PreviousKey:      current.JwtSigningPublicKey,
+			NewKey:           newKey,
+			NewKeyExpiryTime: newExpiry,
+			RotatedAt:        time.Now(),
+		}
+	}
+	instance.handshakeInfo = &updated
+	instance.handshakeInfoLock.Unlock()
+
+	if rotation != nil {
+		instance.emitSigningKeyRotation(*rotation)
+	}
+}
+
+// getJwtSigningPublicKeysForVerification returns the current key, if it has not expired,
+// followed by the previous keys that are still within their grace period
+func (info *handshakeInfo) getJwtSigningPublicKeysForVerification() []string {
+	now := getCurrTimeInMS()
+	result := []string{}
+	if info.JwtSigningPublicKeyExpiryTime > now {
+		result = append(result, info.JwtSigningPublicKey)
+	}
+	for i := len(info.previousJwtSigningPublicKeys) - 1; i >= 0; i-- {
+		if info.previousJwtSigningPublicKeys[i].validUntil > now {
+			result = append(result, info.previousJwtSigningPublicKeys[i].key)
+		}
+	}
+	return result
 }
 
 // ResetHandshakeInfo to be used for testing only
 func ResetHandshakeInfo() {
-	handshakeInfoInstantiated = nil
+	defaultInstance.handshakeInfoLock.Lock()
+	defer defaultInstance.handshakeInfoLock.Unlock()
+	defaultInstance.handshakeInfo = nil
 }
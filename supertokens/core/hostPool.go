@@ -0,0 +1,257 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// HealthCheckOptions configures when a core is taken out of rotation and how it is probed to bring it back
+type HealthCheckOptions struct {
+	// FailureThreshold is the number of consecutive failed queries after which a core is ejected. Defaults to 3
+	FailureThreshold int
+	// ProbeInterval is how often ejected cores are probed. Defaults to 5 seconds
+	ProbeInterval time.Duration
+	// ProbePath is queried with a GET request to check if an ejected core has recovered. Defaults to /hello
+	ProbePath string
+}
+
+// HostStatus is the health of one core in the pool
+type HostStatus struct {
+	Host                string
+	Healthy             bool
+	ConsecutiveFailures int
+	LastFailure         time.Time
+	LastError           string
+}
+
+const defaultFailureThreshold = 3
+const defaultProbeInterval = 5 * time.Second
+const defaultProbePath = "/hello"
+
+type coreHost struct {
+	url                 string
+	healthy             bool
+	consecutiveFailures int
+	lastFailure         time.Time
+	lastError           string
+}
+
+type hostPool struct {
+	hosts            []*coreHost
+	lastTriedIndex   int
+	failureThreshold int
+	probeInterval    time.Duration
+	probePath        string
+	probing          bool
+	lock             sync.Mutex
+}
+
+func newHostPool(hosts []string, options *HealthCheckOptions) *hostPool {
+	pool := &hostPool{
+		hosts:            []*coreHost{},
+		lastTriedIndex:   0,
+		failureThreshold: defaultFailureThreshold,
+		probeInterval:    defaultProbeInterval,
+		probePath:        defaultProbePath,
+	}
+	if options != nil {
+		if options.FailureThreshold > 0 {
+			pool.failureThreshold = options.FailureThreshold
+		}
+		if options.ProbeInterval > 0 {
+			pool.probeInterval = options.ProbeInterval
+		}
+		if options.ProbePath != "" {
+			pool.probePath = options.ProbePath
+		}
+	}
+	for _, host := range hosts {
+		pool.hosts = append(pool.hosts, &coreHost{
+			url:     host,
+			healthy: true,
+		})
+	}
+	return pool
+}
+
+func (pool *hostPool) size() int {
+	return len(pool.hosts)
+}
+
+// getNextHost returns the next healthy host in rotation. If every host has been ejected,
+// it round-robins over all of them so that queries are still attempted.
+func (pool *hostPool) getNextHost() string {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	for i := 0; i < len(pool.hosts); i++ {
+		host := pool.hosts[pool.lastTriedIndex]
+		pool.lastTriedIndex = (pool.lastTriedIndex + 1) % len(pool.hosts)
+		if host.healthy {
+			return host.url
+		}
+	}
+	host := pool.hosts[pool.lastTriedIndex]
+	pool.lastTriedIndex = (pool.lastTriedIndex + 1) % len(pool.hosts)
+	return host.url
+}
+
+func (pool *hostPool) markSuccess(url string) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	host := pool.getHost(url)
+	if host == nil {
+		return
+	}
+	host.healthy = true
+	host.consecutiveFailures = 0
+}
+
+// markFailure records a failed query and returns true if the host was ejected because of it
+func (pool *hostPool) markFailure(url string, err error) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	host := pool.getHost(url)
+	if host == nil {
+		return false
+	}
+	host.consecutiveFailures++
+	host.lastFailure = time.Now()
+	if err != nil {
+		host.lastError = err.Error()
+	}
+	if host.healthy && host.consecutiveFailures >= pool.failureThreshold {
+		host.healthy = false
+		return true
+	}
+	return false
+}
+
+func (pool *hostPool) getHost(url string) *coreHost {
+	for _, host := range pool.hosts {
+		if host.url == url {
+			return host
+		}
+	}
+	return nil
+}
+
+// getUnhealthyHosts must be called with the pool lock held
+func (pool *hostPool) getUnhealthyHosts() []string {
+	result := []string{}
+	for _, host := range pool.hosts {
+		if !host.healthy {
+			result = append(result, host.url)
+		}
+	}
+	return result
+}
+
+func (pool *hostPool) getStatus() []HostStatus {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	result := []HostStatus{}
+	for _, host := range pool.hosts {
+		result = append(result, HostStatus{
+			Host:                host.url,
+			Healthy:             host.healthy,
+			ConsecutiveFailures: host.consecutiveFailures,
+			LastFailure:         host.lastFailure,
+			LastError:           host.lastError,
+		})
+	}
+	return result
+}
+
+// startProbing starts a background goroutine that probes ejected hosts until all of them have recovered,
+// or until closed is closed. Only one such goroutine runs per pool.
+func (pool *hostPool) startProbing(probe func(ctx context.Context, url string) error, closed <-chan struct{}) {
+	pool.lock.Lock()
+	if pool.probing {
+		pool.lock.Unlock()
+		return
+	}
+	pool.probing = true
+	pool.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(pool.probeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closed:
+				pool.lock.Lock()
+				pool.probing = false
+				pool.lock.Unlock()
+				return
+			case <-ticker.C:
+			}
+			pool.lock.Lock()
+			unhealthyHosts := pool.getUnhealthyHosts()
+			if len(unhealthyHosts) == 0 {
+				pool.probing = false
+				pool.lock.Unlock()
+				return
+			}
+			pool.lock.Unlock()
+			for _, url := range unhealthyHosts {
+				ctx, cancel := context.WithTimeout(context.Background(), pool.probeInterval)
+				err := probe(ctx, url)
+				cancel()
+				if err == nil {
+					pool.markSuccess(url)
+				} else {
+					pool.markFailure(url, err)
+				}
+			}
+		}
+	}()
+}
+
+// probeHost queries the probe path of a core and returns an error if it did not respond successfully
+func (querierInstance *querier) probeHost(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url+querierInstance.hostPool.probePath, nil)
+	if err != nil {
+		return err
+	}
+	if querierInstance.apiKey != "" {
+		req.Header.Set("api-key", querierInstance.apiKey)
+	}
+	resp, err := querierInstance.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	// a 401 or 404 means a wrong API key or probe path, so only a 2xx puts the core back in rotation
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.GeneralError{
+			Msg:        "SuperTokens core at " + url + " responded with status " + strconv.Itoa(resp.StatusCode) + " to the health check",
+			StatusCode: resp.StatusCode,
+			Host:       url,
+			Path:       querierInstance.hostPool.probePath,
+			Reason:     errors.ReasonCoreErrorResponse,
+		}
+	}
+	querierInstance.getLogger().Info("querier: core passed the health check and is back in rotation", "host", url)
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func TestHostPoolEjectsAfterConsecutiveFailures(t *testing.T) {
+	pool := newHostPool([]string{"http://a", "http://b"}, &HealthCheckOptions{FailureThreshold: 2})
+	if pool.markFailure("http://a", errors.GeneralError{Msg: "failed"}) {
+		t.Error("should not have been ejected after one failure")
+	}
+	if !pool.markFailure("http://a", errors.GeneralError{Msg: "failed"}) {
+		t.Error("should have been ejected")
+	}
+	for i := 0; i < 4; i++ {
+		if pool.getNextHost() != "http://b" {
+			t.Error("ejected host should not be in rotation")
+		}
+	}
+	pool.markSuccess("http://a")
+	if pool.getNextHost() == pool.getNextHost() {
+		t.Error("recovered host should be back in rotation")
+	}
+}
+
+func TestHostPoolUsesEjectedHostsIfNoneHealthy(t *testing.T) {
+	pool := newHostPool([]string{"http://a"}, &HealthCheckOptions{FailureThreshold: 1})
+	pool.markFailure("http://a", errors.GeneralError{Msg: "failed"})
+	if pool.getNextHost() != "http://a" {
+		t.Error("failed")
+	}
+	status := pool.getStatus()
+	if len(status) != 1 || status[0].Healthy || status[0].ConsecutiveFailures != 1 || status[0].LastError != "failed" {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierProbesEjectedHostUntilItRecovers(t *testing.T) {
+	var down int32 = 1
+	flakyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"versions":["2.3"]}`))
+	}))
+	defer flakyServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions":["2.3"]}`))
+	}))
+	defer server.Close()
+
+	instance := NewInstance(flakyServer.URL+";"+server.URL, "", QuerierOptions{
+		RetryPolicy: &RetryPolicy{},
+		HealthCheck: &HealthCheckOptions{
+			FailureThreshold: 1,
+			ProbeInterval:    20 * time.Millisecond,
+		},
+	})
+	querier := instance.GetQuerier()
+	_, err := querier.SendGetRequest("", "/hello", map[string]string{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if querier.GetHostsStatus()[0].Healthy {
+		t.Error("host should have been ejected")
+	}
+
+	atomic.StoreInt32(&down, 0)
+	start := time.Now()
+	for !querier.GetHostsStatus()[0].Healthy {
+		if time.Since(start) > 2*time.Second {
+			t.Error("host was not brought back after recovering")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHostPoolStopsProbingWhenClosed(t *testing.T) {
+	pool := newHostPool([]string{"http://a"}, &HealthCheckOptions{FailureThreshold: 1, ProbeInterval: 5 * time.Millisecond})
+	pool.markFailure("http://a", errors.GeneralError{Msg: "failed"})
+	var probes int32
+	closed := make(chan struct{})
+	pool.startProbing(func(ctx context.Context, url string) error {
+		atomic.AddInt32(&probes, 1)
+		return errors.GeneralError{Msg: "still down"}
+	}, closed)
+	time.Sleep(30 * time.Millisecond)
+	close(closed)
+	time.Sleep(20 * time.Millisecond)
+	probesWhenClosed := atomic.LoadInt32(&probes)
+	time.Sleep(30 * time.Millisecond)
+	if probesWhenClosed == 0 || atomic.LoadInt32(&probes) != probesWhenClosed {
+		t.Error("probing did not stop when closed")
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.probing {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierKeepsHostEjectedIfProbeIsNot2xx(t *testing.T) {
+	var probes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		w.WriteHeader(404)
+	}))
+	defer server.Close()
+
+	instance := NewInstance(server.URL, "", QuerierOptions{
+		HealthCheck: &HealthCheckOptions{FailureThreshold: 1, ProbeInterval: 5 * time.Millisecond, ProbePath: "/wrong"},
+	})
+	defer instance.Close()
+	querier := instance.GetQuerier()
+	querier.markHostFailure(server.URL, errors.GeneralError{Msg: "failed"})
+	start := time.Now()
+	for atomic.LoadInt32(&probes) < 3 {
+		if time.Since(start) > 2*time.Second {
+			t.Error("host was not probed")
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	status := querier.GetHostsStatus()[0]
+	if status.Healthy || status.LastError == "failed" {
+		t.Error("a 404 from the probe path should not bring the host back")
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// inflightCall is a query whose result is shared by every caller that asked for it while it ran
+type inflightCall struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+// inflightGroup sends a query to the core once for all concurrent callers, like golang.org/x/sync/singleflight.
+// Callers wait for the result only as long as their own ctx allows, so one slow core does not hold up
+// requests that have a shorter deadline
+type inflightGroup struct {
+	call *inflightCall
+	lock sync.Mutex
+}
+
+// do runs query, or waits for the one already running, and returns its result
+func (group *inflightGroup) do(ctx context.Context, query func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	for {
+		group.lock.Lock()
+		call := group.call
+		if call == nil {
+			call = &inflightCall{done: make(chan struct{})}
+			group.call = call
+			group.lock.Unlock()
+			return group.run(ctx, call, query)
+		}
+		group.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.GeneralError{
+				Msg:         ctx.Err().Error(),
+				ActualError: ctx.Err(),
+				Reason:      errors.ReasonContextDone,
+			}
+		case <-call.done:
+		}
+		// the query was aborted by the context of the caller that sent it, so it is sent again with this one
+		if call.err != nil && errors.GetReason(call.err) == errors.ReasonContextDone && ctx.Err() == nil {
+			continue
+		}
+		return call.result, call.err
+	}
+}
+
+// run sends the query of call and publishes its result, even if query panics
+func (group *inflightGroup) run(ctx context.Context, call *inflightCall,
+	query func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	call.err = errors.GeneralError{Msg: "the query to the core did not complete"}
+	defer func() {
+		group.lock.Lock()
+		group.call = nil
+		group.lock.Unlock()
+		close(call.done)
+	}()
+	call.result, call.err = query(ctx)
+	return call.result, call.err
+}
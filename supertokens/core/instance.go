@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"sync"
+)
+
+// Instance holds the querier, handshake info and error handlers for one set of SuperTokens cores
+type Instance struct {
+	querier                  *querier
+	querierLock              sync.RWMutex
+	handshakeInfo            *handshakeInfo
+	handshakeInfoLock        sync.RWMutex
+	handshakeInfoCall        inflightGroup
+	errorHandlers            *errorHandlers
+	errorHandlersOnce        *sync.Once
+	signingKey               signingKeyState
+	signingKeyLock           sync.RWMutex
+	closed                   chan struct{}
+	closeOnce                sync.Once
+	offlineVerifier          *offlineVerifier
+	offlineVerifierLock      sync.RWMutex
+	sessionEventHandlers     []sessionEventHandler
+	sessionEventHandlersLock sync.RWMutex
+	logger                   Logger
+	loggerLock               sync.RWMutex
+	metrics                  Metrics
+	metricsLock              sync.RWMutex
+	tracer                   Tracer
+	tracerLock               sync.RWMutex
+}
+
+var defaultInstance = &Instance{
+	errorHandlersOnce: new(sync.Once),
+	closed:            make(chan struct{}),
+}
+
+// GetDefaultInstance returns the instance used by the package level functions
+func GetDefaultInstance() *Instance {
+	return defaultInstance
+}
+
+// NewInstance creates an instance that queries the given hosts, independent of the default instance
+func NewInstance(hostsStr string, apiKey string, options QuerierOptions) *Instance {
+	instance := &Instance{
+		errorHandlersOnce: new(sync.Once),
+		closed:            make(chan struct{}),
+	}
+	instance.InitQuerier(hostsStr, apiKey, options)
+	return instance
+}
+
+// Close stops the background work of this instance, like refreshing the JWT signing key
+func (instance *Instance) Close() {
+	instance.closeOnce.Do(func() {
+		close(instance.closed)
+	})
+}
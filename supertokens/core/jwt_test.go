@@ -17,7 +17,10 @@
 package core
 
 import (
+	b64 "encoding/base64"
 	"testing"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
 func TestJWTInputOutput(t *testing.T) {
@@ -67,3 +70,34 @@ func TestJWTInvalidKeyInputOutput(t *testing.T) {
 		t.Error("should have failed")
 	}
 }
+
+func TestGetAccessTokenMetadataOfExpiredToken(t *testing.T) {
+	metadata, err := GetAccessTokenMetadata("eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCIsInZlcnNpb24iOiIyIn0=.eyJzZXNzaW9uSGFuZGxlIjoiY2ZmZmVkNGMtOTBiYi00M2IxLTlkZmUtMTViYzJmYmMwM2MyIiwidXNlcklkIjoiIiwicmVmcmVzaFRva2VuSGFzaDEiOiJjNjIzZTE4ODgzMThkZWM5OGZiNzE2YTY4OGZjNGVmZDM3YTM2ZGU3ZTNkNWEzZWFmYjVkMWNiYWVlYzU5ODlmIiwidXNlckRhdGEiOnt9LCJhbnRpQ3NyZlRva2VuIjoiNzc2ZjMwNmMtMzMxZS00ODZhLWFkNmQtZjYzOThlN2M0MzExIiwiZXhwaXJ5VGltZSI6MTU5MTUxNTgxMzkxOCwidGltZUNyZWF0ZWQiOjE1OTE1MTIyMTM5MTgsImlzUGFpZCI6dHJ1ZSwibG1ydCI6MTU5MTUxMjIxMzkxNn0=.W6KbJ2sh4qFBEiab5rCpaQ10vwzjn5fqlRzkIPQ+21wld4rsYuZcfbUx28dxFpH6As/t+S7agZhynDJmCeMVBkE1eIRVzawZhLOLiQ7HYrFJF2FOLFOK6faL2vGaptjqO1pNKIccLqn0yki/OFeBp6O2BH9IZwAKX6E5dVAMlCHccMDPmKbrpXu3KLP6dJqxpg2FotASay3EVP90uDl7dDNY4xxaF9WkYDuiKnFYhGbqsScmsSZSg4DPgyQHW1EfVq78TEKGaaJqnkcYrFT7fPqiQP2p5jQ/0OJ9lX8kQY2Cd6dpoyhaRQ0bZi+PgZiB9mRFI1Bh8c8jOwL/GsXQ7Q==")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if metadata.SessionHandle != "cfffed4c-90bb-43b1-9dfe-15bc2fbc03c2" ||
+		metadata.Expiry != 1591515813918 ||
+		metadata.TimeCreated != 1591512213918 ||
+		metadata.AntiCsrfToken == nil || *metadata.AntiCsrfToken != "776f306c-331e-486a-ad6d-f6398e7c4311" ||
+		metadata.ParentRefreshTokenHash1 != nil {
+		t.Error("returned metadata is invalid")
+	}
+	if _, err := GetAccessTokenMetadata("not a jwt"); err == nil {
+		t.Error("failed")
+	}
+}
+
+func TestGetAccessTokenMetadataOfMalformedPayload(t *testing.T) {
+	for _, payload := range []string{
+		`{"sessionHandle":5,"userId":"","refreshTokenHash1":"","userData":{},"expiryTime":1,"timeCreated":1}`,
+		`{"sessionHandle":"","userId":"","refreshTokenHash1":"","userData":[],"expiryTime":1,"timeCreated":1}`,
+		`{"sessionHandle":"","userId":"","refreshTokenHash1":"","userData":{},"expiryTime":"soon","timeCreated":1}`,
+	} {
+		_, err := GetAccessTokenMetadata("header." + b64.StdEncoding.EncodeToString([]byte(payload)) + ".signature")
+		if !errors.IsTryRefreshTokenError(err) || errors.GetReason(err) != errors.ReasonAccessTokenInvalid {
+			t.Error("malformed payload should return an error: " + payload)
+		}
+	}
+}
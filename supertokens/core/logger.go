@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// debugEnvVar enables logging to stderr when it contains debugNamespace, for example DEBUG=com.supertokens
+const debugEnvVar = "DEBUG"
+const debugNamespace = "com.supertokens"
+
+var debugEnabled = strings.Contains(os.Getenv(debugEnvVar), debugNamespace)
+
+// Logger receives structured log events. args are alternating keys and values.
+// A *slog.Logger can be used as a Logger
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(msg string, args ...interface{}) {}
+func (noopLogger) Info(msg string, args ...interface{})  {}
+func (noopLogger) Warn(msg string, args ...interface{})  {}
+func (noopLogger) Error(msg string, args ...interface{}) {}
+
+// textLogger writes events as "LEVEL msg key=value ..." lines, like the text handler of slog
+type textLogger struct {
+	logger *log.Logger
+}
+
+// NewTextLogger returns a Logger that writes every event to w
+func NewTextLogger(w io.Writer) Logger {
+	return textLogger{
+		logger: log.New(w, debugNamespace+" ", log.LstdFlags|log.Lmicroseconds),
+	}
+}
+
+func (l textLogger) Debug(msg string, args ...interface{}) { l.log("DEBUG", msg, args) }
+func (l textLogger) Info(msg string, args ...interface{})  { l.log("INFO", msg, args) }
+func (l textLogger) Warn(msg string, args ...interface{})  { l.log("WARN", msg, args) }
+func (l textLogger) Error(msg string, args ...interface{}) { l.log("ERROR", msg, args) }
+
+func (l textLogger) log(level string, msg string, args []interface{}) {
+	var line strings.Builder
+	line.WriteString(level)
+	line.WriteString(" ")
+	line.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		key := fmt.Sprint(args[i])
+		var value interface{} = "!MISSING"
+		if i+1 < len(args) {
+			value = args[i+1]
+		}
+		line.WriteString(" ")
+		line.WriteString(key)
+		line.WriteString("=")
+		line.WriteString(fmt.Sprintf("%q", fmt.Sprint(value)))
+	}
+	l.logger.Print(line.String())
+}
+
+var debugLogger = NewTextLogger(os.Stderr)
+
+func getDefaultLogger() Logger {
+	if debugEnabled {
+		return debugLogger
+	}
+	return noopLogger{}
+}
+
+// SetLogger sets the logger of this instance. With nil, nothing is logged unless the DEBUG
+// environment variable contains com.supertokens, in which case events are written to stderr
+func (instance *Instance) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = getDefaultLogger()
+	}
+	instance.loggerLock.Lock()
+	defer instance.loggerLock.Unlock()
+	instance.logger = logger
+}
+
+// GetLogger returns the logger of this instance
+func (instance *Instance) GetLogger() Logger {
+	instance.loggerLock.RLock()
+	logger := instance.logger
+	instance.loggerLock.RUnlock()
+	if logger == nil {
+		return getDefaultLogger()
+	}
+	return logger
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) record(msg string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.messages = append(l.messages, msg)
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record(msg) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record(msg) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record(msg) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record(msg) }
+
+func (l *recordingLogger) contains(msg string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, message := range l.messages {
+		if message == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTextLoggerFormat(t *testing.T) {
+	var out bytes.Buffer
+	NewTextLogger(&out).Warn("querier: request failed", "host", "http://localhost:3567", "status", 500, "dangling")
+	line := out.String()
+	if !strings.Contains(line, `WARN querier: request failed host="http://localhost:3567" status="500" dangling="!MISSING"`) {
+		t.Error("failed: " + line)
+	}
+}
+
+func TestGetSessionLogsDecisions(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+	logger := &recordingLogger{}
+	instance.SetLogger(logger)
+
+	payload := map[string]interface{}{
+		"sessionHandle":     "handle",
+		"userId":            "userId",
+		"refreshTokenHash1": "hash",
+		"userData":          map[string]interface{}{},
+		"expiryTime":        getCurrTimeInMS() + 3600000,
+		"timeCreated":       getCurrTimeInMS(),
+	}
+	if _, err := instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, false); err != nil {
+		t.Error(err)
+	}
+	if !logger.contains("querier: sending request") || !logger.contains("getSession: verified the access token locally") {
+		t.Error("failed")
+	}
+
+	payload["parentRefreshTokenHash1"] = "parentHash"
+	if _, err := instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, false); err != nil {
+		t.Error(err)
+	}
+	if !logger.contains("getSession: the access token must be checked by the core") ||
+		!logger.contains("getSession: verified by the core") {
+		t.Error("failed")
+	}
+}
+
+func TestDefaultLoggerIsNoop(t *testing.T) {
+	instance := NewInstance("http://localhost:3567", "", QuerierOptions{})
+	if _, ok := instance.GetLogger().(noopLogger); !ok && !debugEnabled {
+		t.Error("failed")
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package core
+
+import (
+	"time"
+)
+
+// Names of the metrics recorded by the SDK
+const (
+	// MetricSessionEvents counts session events, with the event's type in the "type" attribute
+	MetricSessionEvents = "supertokens.session.events"
+	// MetricSessionVerifications counts verified access tokens, with "local", "offline" or "core" in the "method" attribute
+	MetricSessionVerifications = "supertokens.session.verifications"
+	// MetricCoreFailovers counts queries that were retried on another core, with the failed core in the "host" attribute
+	MetricCoreFailovers = "supertokens.core.failovers"
+	// MetricCoreRequestDuration is the duration of each query to a core, with the "path", "host" and "status" attributes.
+	// The status is "error" if no response was received
+	MetricCoreRequestDuration = "supertokens.core.request.duration"
+)
+
+// Metrics receives the measurements of the SDK. It must be safe for concurrent use
+type Metrics interface {
+	// IncCounter adds one to the counter with the given name and attributes
+	IncCounter(name string, attributes map[string]string)
+	// RecordDuration records a duration in the histogram with the given name and attributes
+	RecordDuration(name string, duration time.Duration, attributes map[string]string)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) IncCounter(string, map[string]string)                    {}
+func (noopMetrics) RecordDuration(string, time.Duration, map[string]string) {}
+
+// SetMetrics sets where this instance records its metrics. With nil, nothing is recorded
+func (instance *Instance) SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+	instance.metricsLock.Lock()
+	defer instance.metricsLock.Unlock()
+	instance.metrics = metrics
+}
+
+// GetMetrics returns where this instance records its metrics
+func (instance *Instance) GetMetrics() Metrics {
+	instance.metricsLock.RLock()
+	defer instance.metricsLock.RUnlock()
+	if instance.metrics == nil {
+		return noopMetrics{}
+	}
+	return instance.metrics
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	lock      sync.Mutex
+	counters  map[string]int
+	durations map[string]int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		counters:  map[string]int{},
+		durations: map[string]int{},
+	}
+}
+
+func (m *recordingMetrics) IncCounter(name string, attributes map[string]string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for key, value := range attributes {
+		m.counters[name+" "+key+"="+value]++
+	}
+}
+
+func (m *recordingMetrics) RecordDuration(name string, duration time.Duration, attributes map[string]string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.durations[name+" path="+attributes["path"]+" status="+attributes["status"]]++
+}
+
+func TestGetSessionRecordsMetrics(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+	metrics := newRecordingMetrics()
+	instance.SetMetrics(metrics)
+
+	payload := map[string]interface{}{
+		"sessionHandle":     "handle",
+		"userId":            "userId",
+		"refreshTokenHash1": "hash",
+		"userData":          map[string]interface{}{},
+		"expiryTime":        getCurrTimeInMS() + 3600000,
+		"timeCreated":       getCurrTimeInMS(),
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, false); err != nil {
+			t.Error(err)
+		}
+	}
+	payload["parentRefreshTokenHash1"] = "parentHash"
+	if _, err := instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, false); err != nil {
+		t.Error(err)
+	}
+	instance.EmitSessionEvent(SessionEvent{Type: SessionEventCreated})
+
+	if metrics.counters[MetricSessionVerifications+" method=local"] != 2 ||
+		metrics.counters[MetricSessionVerifications+" method=core"] != 1 ||
+		metrics.counters[MetricSessionEvents+" type=SESSION_CREATED"] != 1 {
+		t.Error("failed")
+	}
+	if metrics.durations[MetricCoreRequestDuration+" path=/handshake status=200"] != 1 ||
+		metrics.durations[MetricCoreRequestDuration+" path=/session/verify status=200"] != 1 {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierRecordsFailovers(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	instance := NewInstance(c.server.URL+";http://localhost:1", "", QuerierOptions{})
+	if _, err := instance.GetQuerier().GetAPIVersion(); err != nil {
+		t.Error(err)
+	}
+	metrics := newRecordingMetrics()
+	instance.SetMetrics(metrics)
+
+	// the api version was fetched from the first core, so the handshake is sent to the second one first
+	if _, err := instance.GetHandshakeInfo(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if metrics.counters[MetricCoreFailovers+" host=http://localhost:1"] != 1 ||
+		metrics.durations[MetricCoreRequestDuration+" path=/handshake status=error"] != 1 {
+		t.Error("failed")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	b64 "encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// OfflineVerificationOptions pins the JWT signing public key so that access tokens are verified without querying the core.
+// The key is the base64 encoded public key returned by the core's handshake, or a PEM encoded public key
+type OfflineVerificationOptions struct {
+	// JWTSigningPublicKey is used as is
+	JWTSigningPublicKey string
+	// JWTSigningPublicKeyPath is a file containing the key. It is read again whenever it changes
+	JWTSigningPublicKeyPath string
+}
+
+const offlineKeyFileCheckInterval = time.Second
+
+type offlineVerifier struct {
+	key           string
+	path          string
+	checkInterval time.Duration
+	lastChecked   time.Time
+	modTime       time.Time
+	size          int64
+	loadError     error
+	lock          sync.Mutex
+}
+
+// SetOfflineVerification makes GetSession verify access tokens only with the given key, never querying the core.
+// Passing nil goes back to verifying with the key from the handshake. An error is returned if the key file cannot be read,
+// in which case it is read again on the next verification
+func (instance *Instance) SetOfflineVerification(options *OfflineVerificationOptions) error {
+	var verifier *offlineVerifier = nil
+	var err error = nil
+	if options != nil {
+		verifier = &offlineVerifier{
+			key:           normaliseJwtSigningPublicKey(options.JWTSigningPublicKey),
+			path:          options.JWTSigningPublicKeyPath,
+			checkInterval: offlineKeyFileCheckInterval,
+		}
+		if verifier.path != "" {
+			verifier.lock.Lock()
+			err = verifier.reload()
+			verifier.lock.Unlock()
+		} else if verifier.key == "" {
+			err = errors.GeneralError{
+				Msg:    "Please provide either a JWT signing public key or a path to it for offline verification",
+				Reason: errors.ReasonInvalidConfig,
+			}
+		}
+	}
+	instance.offlineVerifierLock.Lock()
+	defer instance.offlineVerifierLock.Unlock()
+	instance.offlineVerifier = verifier
+	return err
+}
+
+func (instance *Instance) getOfflineVerifier() *offlineVerifier {
+	instance.offlineVerifierLock.RLock()
+	defer instance.offlineVerifierLock.RUnlock()
+	return instance.offlineVerifier
+}
+
+// IsOfflineVerificationEnabled returns true if access tokens are verified only with a pinned key
+func (instance *Instance) IsOfflineVerificationEnabled() bool {
+	return instance.getOfflineVerifier() != nil
+}
+
+// getJwtSigningPublicKey returns the pinned key, reading the key file again if it changed
+func (verifier *offlineVerifier) getJwtSigningPublicKey() (string, error) {
+	verifier.lock.Lock()
+	defer verifier.lock.Unlock()
+	if verifier.path != "" && (verifier.loadError != nil || time.Since(verifier.lastChecked) >= verifier.checkInterval) {
+		verifier.reload()
+	}
+	if verifier.loadError != nil {
+		return "", verifier.loadError
+	}
+	return verifier.key, nil
+}
+
+// reload must be called with the verifier lock held. The last key that was read successfully is kept if the file is unreadable
+func (verifier *offlineVerifier) reload() error {
+	verifier.lastChecked = time.Now()
+	stat, err := os.Stat(verifier.path)
+	if err != nil {
+		verifier.loadError = err
+		return err
+	}
+	if verifier.loadError == nil && verifier.key != "" &&
+		stat.ModTime().Equal(verifier.modTime) && stat.Size() == verifier.size {
+		return nil
+	}
+	content, err := ioutil.ReadFile(verifier.path)
+	if err != nil {
+		verifier.loadError = err
+		return err
+	}
+	key := normaliseJwtSigningPublicKey(string(content))
+	if key == "" {
+		verifier.loadError = errors.GeneralError{
+			Msg:    "JWT signing public key file is empty: " + verifier.path,
+			Reason: errors.ReasonSigningKeyUnavailable,
+		}
+		return verifier.loadError
+	}
+	verifier.key = key
+	verifier.modTime = stat.ModTime()
+	verifier.size = stat.Size()
+	verifier.loadError = nil
+	return nil
+}
+
+// normaliseJwtSigningPublicKey converts a PEM encoded key to the base64 form used by the core
+func normaliseJwtSigningPublicKey(key string) string {
+	key = strings.TrimSpace(key)
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		return b64.StdEncoding.EncodeToString(block.Bytes)
+	}
+	return key
+}
+
+// getSession verifies the access token with the pinned key only
+func (verifier *offlineVerifier) getSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool) (SessionInfo, error) {
+	jwtSigningPublicKey, err := verifier.getJwtSigningPublicKey()
+	if err != nil {
+		return SessionInfo{}, errors.OfflineVerificationError{
+			Msg:         "Could not load the JWT signing public key: " + err.Error(),
+			ActualError: err,
+			Reason:      errors.ReasonSigningKeyUnavailable,
+		}
+	}
+	accessTokenInfo, err := getInfoFromAccessToken(accessToken, []string{jwtSigningPublicKey}, false)
+	if err != nil {
+		// an expired token is fixed by refreshing it, like when the core verifies it
+		if errors.GetReason(err) == errors.ReasonAccessTokenExpired {
+			return SessionInfo{}, err
+		}
+		return SessionInfo{}, errors.OfflineVerificationError{
+			Msg:    err.Error(),
+			Reason: errors.GetReason(err),
+		}
+	}
+	if doAntiCsrfCheck && accessTokenInfo.antiCsrfToken != nil &&
+		(antiCsrfToken == nil || *antiCsrfToken != *(accessTokenInfo.antiCsrfToken)) {
+		return SessionInfo{}, errors.TryRefreshTokenError{
+			Msg:    "anti-csrf check failed",
+			Reason: errors.ReasonAntiCsrfCheckFailed,
+		}
+	}
+	return SessionInfo{
+		Handle:        accessTokenInfo.sessionHandle,
+		UserID:        accessTokenInfo.userID,
+		UserDataInJWT: accessTokenInfo.userData,
+	}, nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func newUnreachableInstance(t *testing.T) (*Instance, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("core should not have been queried: " + r.URL.Path)
+	}))
+	return NewInstance(server.URL, "", QuerierOptions{}), server.Close
+}
+
+func TestOfflineVerificationWithPinnedKey(t *testing.T) {
+	c := newTestCore(t)
+	c.server.Close()
+	instance, closeServer := newUnreachableInstance(t)
+	defer closeServer()
+	if err := instance.SetOfflineVerification(&OfflineVerificationOptions{JWTSigningPublicKey: c.getPublicKey()}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	payload := localAccessTokenPayload()
+	payload["parentRefreshTokenHash1"] = "parentHash"
+	session, err := instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if session.UserID != "userId" || session.Handle != "handle" {
+		t.Error("failed")
+	}
+
+	c.rotateKey(t)
+	_, err = instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, false)
+	if !errors.IsOfflineVerificationError(err) {
+		t.Error("token signed with another key should not be verified")
+	}
+	if errors.IsTryRefreshTokenError(err) {
+		t.Error("offline verification errors should not ask the frontend to refresh")
+	}
+}
+
+func TestOfflineVerificationChecksAntiCsrfToken(t *testing.T) {
+	c := newTestCore(t)
+	c.server.Close()
+	instance, closeServer := newUnreachableInstance(t)
+	defer closeServer()
+	instance.SetOfflineVerification(&OfflineVerificationOptions{JWTSigningPublicKey: c.getPublicKey()})
+
+	payload := localAccessTokenPayload()
+	payload["antiCsrfToken"] = "csrf"
+	token := c.createAccessToken(t, payload)
+	wrong := "wrong"
+	_, err := instance.GetSession(context.Background(), token, &wrong, true)
+	if !errors.IsTryRefreshTokenError(err) || errors.GetReason(err) != errors.ReasonAntiCsrfCheckFailed {
+		t.Error("failed")
+	}
+	right := "csrf"
+	if _, err := instance.GetSession(context.Background(), token, &right, true); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOfflineVerificationAsksToRefreshExpiredTokens(t *testing.T) {
+	c := newTestCore(t)
+	c.server.Close()
+	instance, closeServer := newUnreachableInstance(t)
+	defer closeServer()
+	instance.SetOfflineVerification(&OfflineVerificationOptions{JWTSigningPublicKey: c.getPublicKey()})
+
+	payload := localAccessTokenPayload()
+	payload["expiryTime"] = getCurrTimeInMS() - 1000
+	_, err := instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, false)
+	if !errors.IsTryRefreshTokenError(err) || errors.IsOfflineVerificationError(err) {
+		t.Error("an expired token should be refreshed")
+	}
+	if errors.GetReason(err) != errors.ReasonAccessTokenExpired {
+		t.Error("failed")
+	}
+}
+
+func TestOfflineVerificationReloadsKeyFile(t *testing.T) {
+	c := newTestCore(t)
+	c.server.Close()
+	instance, closeServer := newUnreachableInstance(t)
+	defer closeServer()
+
+	dir, err := ioutil.TempDir("", "supertokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key.pem")
+	pemKey := "-----BEGIN PUBLIC KEY-----\n" + c.getPublicKey() + "\n-----END PUBLIC KEY-----\n"
+	if err := ioutil.WriteFile(path, []byte(pemKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := instance.SetOfflineVerification(&OfflineVerificationOptions{JWTSigningPublicKeyPath: path}); err != nil {
+		t.Error(err)
+		return
+	}
+	instance.getOfflineVerifier().checkInterval = 0
+
+	oldToken := c.createAccessToken(t, localAccessTokenPayload())
+	if _, err := instance.GetSession(context.Background(), oldToken, nil, false); err != nil {
+		t.Error(err)
+	}
+
+	c.rotateKey(t)
+	if err := ioutil.WriteFile(path, []byte(c.getPublicKey()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := instance.GetSession(context.Background(), c.createAccessToken(t, localAccessTokenPayload()), nil, false); err != nil {
+		t.Error(err)
+	}
+	if _, err := instance.GetSession(context.Background(), oldToken, nil, false); !errors.IsOfflineVerificationError(err) {
+		t.Error("key file change was not picked up")
+	}
+}
+
+func TestOfflineVerificationWithMissingKeyFile(t *testing.T) {
+	instance, closeServer := newUnreachableInstance(t)
+	defer closeServer()
+	err := instance.SetOfflineVerification(&OfflineVerificationOptions{JWTSigningPublicKeyPath: "/does/not/exist"})
+	if err == nil {
+		t.Error("failed")
+	}
+	if _, err := instance.GetSession(context.Background(), "token", nil, false); !errors.IsOfflineVerificationError(err) {
+		t.Error("failed")
+	}
+}
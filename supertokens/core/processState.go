@@ -30,18 +30,18 @@ var processStateLock sync.Mutex
 
 // ResetProcessState to be used for testing only
 func ResetProcessState() {
+	processStateLock.Lock()
+	defer processStateLock.Unlock()
 	processStateInstantiated = nil
 }
 
 // GetProcessStateInstance used to get processState struct
 func GetProcessStateInstance() *processState {
+	processStateLock.Lock()
+	defer processStateLock.Unlock()
 	if processStateInstantiated == nil {
-		processStateLock.Lock()
-		defer processStateLock.Unlock()
-		if processStateInstantiated == nil {
-			processStateInstantiated = &processState{
-				history: []int{},
-			}
+		processStateInstantiated = &processState{
+			history: []int{},
 		}
 	}
 	return processStateInstantiated
@@ -56,7 +56,8 @@ func (p *processState) GetLastEventByName(state int) *int {
 
 	for i := len(p.history) - 1; i >= 0; i-- {
 		if p.history[i] == state {
-			return &p.history[i]
+			temp := p.history[i]
+			return &temp
 		}
 	}
 	return nil
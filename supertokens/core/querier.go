@@ -18,130 +18,244 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	goerrors "errors"
 	"flag"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
 type querier struct {
-	hosts          []string
-	lastTriedIndex int
+	hostPool       *hostPool
 	apiVersion     *string
 	apiKey         string
+	apiVersionLock sync.RWMutex
+	apiVersionCall inflightGroup
+	httpClient     *http.Client
+	requestTimeout time.Duration
+	retryPolicy    *RetryPolicy
+	getLogger      func() Logger
+	getMetrics     func() Metrics
+	getTracer      func() Tracer
+	// closed stops the background probing of ejected cores when the instance is closed
+	closed <-chan struct{}
 }
 
-var querierInstantiated *querier
-var querierLock sync.Mutex
+// QuerierOptions configures how queries are sent to the SuperTokens cores
+type QuerierOptions struct {
+	// HTTPClient is used for all queries to the core. Defaults to a client using http.DefaultTransport
+	HTTPClient *http.Client
+	// RequestTimeout is the maximum duration of a single attempt of a query. Zero means no timeout
+	RequestTimeout time.Duration
+	// RetryPolicy if set, also retries queries that time out or get a 5xx response. See RetryPolicy
+	RetryPolicy *RetryPolicy
+	// HealthCheck configures when cores are taken out of rotation. Uses the defaults of HealthCheckOptions if nil
+	HealthCheck *HealthCheckOptions
+}
+
+// RetryPolicy decides how often and how quickly failed queries to the core are retried.
+// Every host is tried once without waiting, after which MaxRetries more attempts are made
+// with an exponential backoff starting at InitialBackoff and capped at MaxBackoff.
+//
+// A query whose connection is refused, or whose host cannot be resolved, never reached a core,
+// so it is always sent to the next core, with or without a RetryPolicy. Queries that time out
+// or get a 5xx response may have been processed by the core, so they are only retried with one.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var defaultHTTPClient = &http.Client{}
+
 var hostsAliveForTesting = []string{}
+var hostsAliveForTestingLock sync.Mutex
 
 // ResetQuerier to be used for testing only
 func ResetQuerier() {
-	querierInstantiated = nil
+	defaultInstance.querierLock.Lock()
+	defaultInstance.querier = nil
+	defaultInstance.querierLock.Unlock()
+	hostsAliveForTestingLock.Lock()
 	hostsAliveForTesting = []string{}
+	hostsAliveForTestingLock.Unlock()
 }
 
 // GetQuerierInstance function used to get querier struct
 func GetQuerierInstance() *querier {
-	if querierInstantiated == nil {
-		querierLock.Lock()
-		defer querierLock.Unlock()
-		if querierInstantiated == nil {
-			querierInstantiated = &querier{
-				hosts:          []string{"http://localhost:3567"},
-				lastTriedIndex: 0,
-				apiVersion:     nil,
-				apiKey:         "",
-			}
+	return defaultInstance.GetQuerier()
+}
+
+// GetQuerier function used to get the querier struct of this instance
+func (instance *Instance) GetQuerier() *querier {
+	instance.querierLock.RLock()
+	existing := instance.querier
+	instance.querierLock.RUnlock()
+	if existing != nil {
+		return existing
+	}
+	instance.querierLock.Lock()
+	defer instance.querierLock.Unlock()
+	if instance.querier == nil {
+		instance.querier = &querier{
+			hostPool:   newHostPool([]string{"http://localhost:3567"}, nil),
+			apiVersion: nil,
+			apiKey:     "",
+			httpClient: defaultHTTPClient,
+			getLogger:  instance.GetLogger,
+			getMetrics: instance.GetMetrics,
+			getTracer:  instance.GetTracer,
+			closed:     instance.closed,
 		}
 	}
-	return querierInstantiated
+	return instance.querier
 }
 
 // InitQuerier set hosts
 func InitQuerier(hostsStr string, apiKey string) {
-	if querierInstantiated == nil {
-		querierLock.Lock()
-		defer querierLock.Unlock()
-		if querierInstantiated == nil {
-
-			// convert "http://hostname1:port1;https://hostname2:port2" to proper data type
-			var hostsArr = make([]string, 0)
-			var splitted = strings.Split(hostsStr, ";")
-			for i := 0; i < len(splitted); i++ {
-				var curr = splitted[i]
-				if curr == "" {
-					continue
-				}
-				if curr[len(curr)-1:] == "/" { // remove trailing slash from user
-					curr = curr[0 : len(curr)-1]
-				}
-				hostsArr = append(hostsArr, curr)
+	defaultInstance.InitQuerier(hostsStr, apiKey, QuerierOptions{})
+}
+
+// InitQuerier set hosts and query options for this instance
+func (instance *Instance) InitQuerier(hostsStr string, apiKey string, options QuerierOptions) {
+	instance.querierLock.Lock()
+	defer instance.querierLock.Unlock()
+	if instance.querier == nil {
+
+		// convert "http://hostname1:port1;https://hostname2:port2" to proper data type
+		var hostsArr = make([]string, 0)
+		var splitted = strings.Split(hostsStr, ";")
+		for i := 0; i < len(splitted); i++ {
+			var curr = splitted[i]
+			if curr == "" {
+				continue
 			}
-			querierInstantiated = &querier{
-				hosts:          hostsArr,
-				lastTriedIndex: 0,
-				apiVersion:     nil,
-				apiKey:         apiKey,
+			if curr[len(curr)-1:] == "/" { // remove trailing slash from user
+				curr = curr[0 : len(curr)-1]
 			}
+			hostsArr = append(hostsArr, curr)
+		}
+		httpClient := options.HTTPClient
+		if httpClient == nil {
+			httpClient = defaultHTTPClient
+		}
+		instance.querier = &querier{
+			hostPool:       newHostPool(hostsArr, options.HealthCheck),
+			apiVersion:     nil,
+			apiKey:         apiKey,
+			httpClient:     httpClient,
+			requestTimeout: options.RequestTimeout,
+			retryPolicy:    options.RetryPolicy,
+			getLogger:      instance.GetLogger,
+			getMetrics:     instance.GetMetrics,
+			getTracer:      instance.GetTracer,
+			closed:         instance.closed,
 		}
 	}
 }
 
 // GetAPIVersion get's the supported CDI version
 func (querierInstance *querier) GetAPIVersion() (string, error) {
-	if querierInstance.apiVersion != nil {
-		return *(querierInstance.apiVersion), nil
+	return querierInstance.GetAPIVersionWithContext(context.Background())
+}
+
+// GetAPIVersionWithContext get's the supported CDI version, aborting the query to the core when ctx is done.
+// Concurrent callers share one query to the core
+func (querierInstance *querier) GetAPIVersionWithContext(ctx context.Context) (string, error) {
+	querierInstance.apiVersionLock.RLock()
+	apiVersion := querierInstance.apiVersion
+	querierInstance.apiVersionLock.RUnlock()
+	if apiVersion != nil {
+		return *apiVersion, nil
 	}
-	querierLock.Lock()
-	defer querierLock.Unlock()
-	if querierInstance.apiVersion != nil {
-		return *(querierInstance.apiVersion), nil
+	result, err := querierInstance.apiVersionCall.do(ctx, func(ctx context.Context) (interface{}, error) {
+		querierInstance.apiVersionLock.RLock()
+		apiVersion := querierInstance.apiVersion
+		querierInstance.apiVersionLock.RUnlock()
+		if apiVersion != nil {
+			return *apiVersion, nil
+		}
+		supportedVersion, err := querierInstance.queryAPIVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		querierInstance.apiVersionLock.Lock()
+		querierInstance.apiVersion = &supportedVersion
+		querierInstance.apiVersionLock.Unlock()
+		return supportedVersion, nil
+	})
+	if err != nil {
+		return "", err
 	}
-	response, err := querierInstance.sendRequestHelper("/apiversion", func(url string) (*http.Response, error) {
-		req, err := http.NewRequest("GET", url, nil)
+	return result.(string), nil
+}
+
+// queryAPIVersion asks the core for the CDI versions it supports and returns the largest one this SDK supports too
+func (querierInstance *querier) queryAPIVersion(ctx context.Context) (string, error) {
+	response, err := querierInstance.sendRequestHelper(ctx, "/apiversion", func(ctx context.Context, url string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
-		client := &http.Client{}
-		return client.Do(req)
-	}, len(querierInstance.hosts))
+		querierInstance.getTracer().Inject(ctx, req.Header)
+		return querierInstance.httpClient.Do(req)
+	}, querierInstance.getNumberOfTries())
 
 	if err != nil {
 		return "", err
 	}
 
-	cdiSupportedByServerInterface := response["versions"].([]interface{})
+	cdiSupportedByServerInterface, ok := response["versions"].([]interface{})
+	if !ok {
+		return "", errors.GeneralError{
+			Msg:    "The SuperTokens core responded to /apiversion without a list of versions",
+			Path:   "/apiversion",
+			Reason: errors.ReasonCoreIncompatible,
+		}
+	}
 	cdiSupportedByServer := []string{}
 	for i := 0; i < len(cdiSupportedByServerInterface); i++ {
-		cdiSupportedByServer = append(cdiSupportedByServer, cdiSupportedByServerInterface[i].(string))
+		if version, ok := cdiSupportedByServerInterface[i].(string); ok {
+			cdiSupportedByServer = append(cdiSupportedByServer, version)
+		}
 	}
 
 	supportedVersion := getLargestVersionFromIntersection(cdiSupportedByServer, CdiVersion)
 
 	if supportedVersion == nil {
 		return "", errors.GeneralError{
-			Msg: "The running SuperTokens core version is not compatible with this Golang SDK. Please visit https://supertokens.io/docs/community/compatibility to find the right version",
+			Msg:    "The running SuperTokens core version is not compatible with this Golang SDK. Please visit https://supertokens.io/docs/community/compatibility to find the right version",
+			Path:   "/apiversion",
+			Reason: errors.ReasonCoreIncompatible,
 		}
 	}
-
-	querierInstance.apiVersion = supportedVersion
-
-	return *(querierInstance.apiVersion), nil
+	return *supportedVersion, nil
 }
+
 func (querierInstance *querier) GetHostsAliveForTesting() []string {
-	return hostsAliveForTesting
+	hostsAliveForTestingLock.Lock()
+	defer hostsAliveForTestingLock.Unlock()
+	return append([]string{}, hostsAliveForTesting...)
 }
 
 func (querierInstance *querier) SendPostRequest(requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
+	return querierInstance.SendPostRequestWithContext(context.Background(), requestID, path, data)
+}
+
+// SendPostRequestWithContext sends a POST request to the core, aborting it when ctx is done
+func (querierInstance *querier) SendPostRequestWithContext(ctx context.Context, requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
 	if path == "/session" || path == "/session/verify" || path == "/session/refresh" || path == "/handshake" {
 		data["frontendSDK"] = GetDeviceInfoInstance().GetFrontendSDKs()
 		data["drive"] = map[string]interface{}{
@@ -149,64 +263,80 @@ func (querierInstance *querier) SendPostRequest(requestID string, path string, d
 			"version": VERSION,
 		}
 	}
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
+	// the CDI version is resolved before picking a host, so that an incompatible core or a wrong API key is
+	// returned as is, instead of ejecting the host the query was about to be sent to
+	apiVersion, apiVersionError := querierInstance.GetAPIVersionWithContext(ctx)
+	if apiVersionError != nil {
+		return nil, apiVersionError
+	}
+	return querierInstance.sendRequestHelper(ctx, path, func(ctx context.Context, url string) (*http.Response, error) {
 		jsonData, _ := json.Marshal(data)
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, err
 		}
 
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
-		if apiVersionError != nil {
-			return nil, apiVersionError
-		}
-
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("cdi-version", apiVerion)
+		req.Header.Set("cdi-version", apiVersion)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
+		querierInstance.getTracer().Inject(ctx, req.Header)
 
-		client := getHTTPClient(requestID)
+		client := querierInstance.getHTTPClient(requestID)
 		return client.Do(req)
-	}, len(querierInstance.hosts))
+	}, querierInstance.getNumberOfTries())
 }
 
-func getHTTPClient(requestID string) MockedHTTPClient {
+func (querierInstance *querier) getHTTPClient(requestID string) MockedHTTPClient {
 	mock := GetMockedHTTPClient(requestID)
 	if mock == nil {
-		return &http.Client{}
+		return querierInstance.httpClient
 	}
 	return mock
 }
 
 func (querierInstance *querier) SendDeleteRequest(requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
+	return querierInstance.SendDeleteRequestWithContext(context.Background(), requestID, path, data)
+}
+
+// SendDeleteRequestWithContext sends a DELETE request to the core, aborting it when ctx is done
+func (querierInstance *querier) SendDeleteRequestWithContext(ctx context.Context, requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
+	apiVersion, apiVersionError := querierInstance.GetAPIVersionWithContext(ctx)
+	if apiVersionError != nil {
+		return nil, apiVersionError
+	}
+	return querierInstance.sendRequestHelper(ctx, path, func(ctx context.Context, url string) (*http.Response, error) {
 		jsonData, _ := json.Marshal(data)
-		req, err := http.NewRequest("DELETE", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "DELETE", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, err
 		}
 
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
-		if apiVersionError != nil {
-			return nil, apiVersionError
-		}
-
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("cdi-version", apiVerion)
+		req.Header.Set("cdi-version", apiVersion)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
+		querierInstance.getTracer().Inject(ctx, req.Header)
 
-		client := getHTTPClient(requestID)
+		client := querierInstance.getHTTPClient(requestID)
 		return client.Do(req)
-	}, len(querierInstance.hosts))
+	}, querierInstance.getNumberOfTries())
 }
 
 func (querierInstance *querier) SendGetRequest(requestID string, path string, params map[string]string) (map[string]interface{}, error) {
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
-		req, err := http.NewRequest("GET", url, nil)
+	return querierInstance.SendGetRequestWithContext(context.Background(), requestID, path, params)
+}
+
+// SendGetRequestWithContext sends a GET request to the core, aborting it when ctx is done
+func (querierInstance *querier) SendGetRequestWithContext(ctx context.Context, requestID string, path string, params map[string]string) (map[string]interface{}, error) {
+	apiVersion, apiVersionError := querierInstance.GetAPIVersionWithContext(ctx)
+	if apiVersionError != nil {
+		return nil, apiVersionError
+	}
+	return querierInstance.sendRequestHelper(ctx, path, func(ctx context.Context, url string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -217,90 +347,298 @@ func (querierInstance *querier) SendGetRequest(requestID string, path string, pa
 			q.Add(k, v)
 		}
 		req.URL.RawQuery = q.Encode()
-
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
-		if apiVersionError != nil {
-			return nil, apiVersionError
-		}
-		req.Header.Set("cdi-version", apiVerion)
+		req.Header.Set("cdi-version", apiVersion)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
+		querierInstance.getTracer().Inject(ctx, req.Header)
 
-		client := getHTTPClient(requestID)
+		client := querierInstance.getHTTPClient(requestID)
 		return client.Do(req)
-	}, len(querierInstance.hosts))
+	}, querierInstance.getNumberOfTries())
 }
 
 func (querierInstance *querier) SendPutRequest(requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
+	return querierInstance.SendPutRequestWithContext(context.Background(), requestID, path, data)
+}
+
+// SendPutRequestWithContext sends a PUT request to the core, aborting it when ctx is done
+func (querierInstance *querier) SendPutRequestWithContext(ctx context.Context, requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
+	apiVersion, apiVersionError := querierInstance.GetAPIVersionWithContext(ctx)
+	if apiVersionError != nil {
+		return nil, apiVersionError
+	}
+	return querierInstance.sendRequestHelper(ctx, path, func(ctx context.Context, url string) (*http.Response, error) {
 		jsonData, _ := json.Marshal(data)
-		req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, err
 		}
 
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
-		if apiVersionError != nil {
-			return nil, apiVersionError
-		}
-
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("cdi-version", apiVerion)
+		req.Header.Set("cdi-version", apiVersion)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
+		querierInstance.getTracer().Inject(ctx, req.Header)
 
-		client := getHTTPClient(requestID)
+		client := querierInstance.getHTTPClient(requestID)
 		return client.Do(req)
-	}, len(querierInstance.hosts))
+	}, querierInstance.getNumberOfTries())
 }
 
-type httpRequestFunction func(url string) (*http.Response, error)
+type httpRequestFunction func(ctx context.Context, url string) (*http.Response, error)
 
-func (querierInstance *querier) sendRequestHelper(path string, httpRequest httpRequestFunction,
+func (querierInstance *querier) getNumberOfTries() int {
+	if querierInstance.retryPolicy == nil || querierInstance.hostPool.size() == 0 {
+		return querierInstance.hostPool.size()
+	}
+	return querierInstance.hostPool.size() + querierInstance.retryPolicy.MaxRetries
+}
+
+// getBackoff returns how long to wait before the given attempt. The first attempt on every host is not delayed
+func (querierInstance *querier) getBackoff(attempt int) time.Duration {
+	retry := attempt - querierInstance.hostPool.size()
+	if querierInstance.retryPolicy == nil || retry < 0 {
+		return 0
+	}
+	backoff := querierInstance.retryPolicy.InitialBackoff
+	for i := 0; i < retry; i++ {
+		backoff = backoff * 2
+		if querierInstance.retryPolicy.MaxBackoff > 0 && backoff >= querierInstance.retryPolicy.MaxBackoff {
+			return querierInstance.retryPolicy.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// isRetryableError returns true if the query did not reach the core, or if it timed out and a retry policy is set
+func (querierInstance *querier) isRetryableError(err error) bool {
+	if isCoreUnreachableError(err) {
+		return true
+	}
+	if querierInstance.retryPolicy == nil {
+		return false
+	}
+	if goerrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netError net.Error
+	return goerrors.As(err, &netError) && netError.Timeout()
+}
+
+// isRetryableStatus returns true if the core responded with a 5xx status and a retry policy is set
+func (querierInstance *querier) isRetryableStatus(statusCode int) bool {
+	return querierInstance.retryPolicy != nil && statusCode >= 500
+}
+
+// isCoreUnreachableError returns true if the connection to the core was refused or its host could not be resolved.
+// The core then never got the query, so it is always safe to send it to the next core
+func isCoreUnreachableError(err error) bool {
+	var dnsError *net.DNSError
+	if goerrors.As(err, &dnsError) {
+		return true
+	}
+	var opError *net.OpError
+	return goerrors.As(err, &opError) && goerrors.Is(opError, syscall.ECONNREFUSED)
+}
+
+// markHostFailure records a failed query to a core, and starts probing it in the background if it got ejected
+func (querierInstance *querier) markHostFailure(host string, err error) {
+	if querierInstance.hostPool.markFailure(host, err) {
+		querierInstance.getLogger().Warn("querier: core taken out of rotation", "host", host, "error", err)
+		querierInstance.hostPool.startProbing(querierInstance.probeHost, querierInstance.closed)
+	}
+}
+
+// GetHostsStatus returns the health of every core this querier sends queries to
+func (querierInstance *querier) GetHostsStatus() []HostStatus {
+	return querierInstance.hostPool.getStatus()
+}
+
+// getCoreResponseError reads the body of a 4xx or 5xx response from the core into an error.
+// The API key and unsupported API cases get their own errors since they are configuration problems
+func getCoreResponseError(resp *http.Response, host string, path string) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	cdiVersion := ""
+	if resp.Request != nil {
+		cdiVersion = resp.Request.Header.Get("cdi-version")
+	}
+	coreResponseError := errors.CoreResponseError{
+		Msg: "SuperTokens core at " + host + " responded with status " + strconv.Itoa(resp.StatusCode) +
+			" to " + path + ": " + string(body),
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Host:       host,
+		Path:       path,
+		CDIVersion: cdiVersion,
+		Reason:     errors.ReasonCoreErrorResponse,
+	}
+	if resp.StatusCode == 401 {
+		coreResponseError.Msg = "SuperTokens core at " + host + " rejected the API key for " + path +
+			". Please make sure the APIKey in your config is one of the api_keys in the core's config"
+		coreResponseError.Reason = errors.ReasonInvalidAPIKey
+		return errors.InvalidAPIKeyError{CoreResponseError: coreResponseError}
+	}
+	if resp.StatusCode == 404 {
+		coreResponseError.Msg = "SuperTokens core at " + host + " does not support " + path +
+			" (CDI version " + cdiVersion + "). Please visit https://supertokens.io/docs/community/compatibility to find a compatible core version"
+		coreResponseError.Reason = errors.ReasonUnsupportedEndpoint
+		return errors.UnsupportedEndpointError{CoreResponseError: coreResponseError}
+	}
+	return coreResponseError
+}
+
+// getTransportErrorReason tells apart queries that were aborted by the caller from cores that could not be reached
+func getTransportErrorReason(ctx context.Context) errors.Reason {
+	if ctx.Err() != nil {
+		return errors.ReasonContextDone
+	}
+	return errors.ReasonCoreUnreachable
+}
+
+func (querierInstance *querier) sendRequestHelper(ctx context.Context, path string, httpRequest httpRequestFunction,
 	numberOfTries int) (map[string]interface{}, error) {
-	if numberOfTries == 0 {
+	if ctxError := ctx.Err(); ctxError != nil {
+		return nil, errors.GeneralError{
+			Msg:         ctxError.Error(),
+			ActualError: ctxError,
+			Path:        path,
+			Reason:      errors.ReasonContextDone,
+		}
+	}
+	if numberOfTries <= 0 {
 		return nil, errors.GeneralError{
 			Msg:         "No SuperTokens core available to query",
 			ActualError: nil,
+			Path:        path,
+			Reason:      errors.ReasonNoCoreAvailable,
 		}
 	}
-	var currentHost = querierInstance.hosts[querierInstance.lastTriedIndex]
-	querierInstance.lastTriedIndex = (querierInstance.lastTriedIndex + 1) % len(querierInstance.hosts)
-	var resp, err = httpRequest(currentHost + path)
 
-	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") {
-			return querierInstance.sendRequestHelper(path, httpRequest, numberOfTries-1)
+	if backoff := querierInstance.getBackoff(querierInstance.getNumberOfTries() - numberOfTries); backoff > 0 {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.GeneralError{
+				Msg:         ctx.Err().Error(),
+				ActualError: ctx.Err(),
+				Path:        path,
+				Reason:      errors.ReasonContextDone,
+			}
+		case <-timer.C:
+		}
+	}
+
+	attemptCtx := ctx
+	if querierInstance.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, querierInstance.requestTimeout)
+		defer cancel()
+	}
+
+	logger := querierInstance.getLogger()
+	var currentHost = querierInstance.hostPool.getNextHost()
+	logger.Debug("querier: sending request", "host", currentHost, "path", path, "triesLeft", numberOfTries)
+	metrics := querierInstance.getMetrics()
+	spanCtx, span := querierInstance.getTracer().StartSpan(attemptCtx, "supertokens.core"+path, SpanKindClient)
+	span.SetAttribute("server.address", currentHost)
+	span.SetAttribute("url.path", path)
+	span.SetAttribute("supertokens.retry_count", querierInstance.getNumberOfTries()-numberOfTries)
+	requestStart := time.Now()
+	var resp, err = httpRequest(spanCtx, currentHost+path)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+		span.SetAttribute("http.response.status_code", resp.StatusCode)
+		if resp.Request != nil {
+			span.SetAttribute("supertokens.cdi_version", resp.Request.Header.Get("cdi-version"))
 		}
+		if resp.StatusCode >= 400 {
+			span.RecordError(errors.GeneralError{Msg: "core responded with status " + status})
+		}
+	} else {
+		span.RecordError(err)
+	}
+	span.End()
+	metrics.RecordDuration(MetricCoreRequestDuration, time.Since(requestStart), map[string]string{
+		"path":   path,
+		"host":   currentHost,
+		"status": status,
+	})
+
+	if err != nil {
 		if resp != nil {
 			resp.Body.Close()
 		}
+		if ctx.Err() == nil {
+			querierInstance.markHostFailure(currentHost, err)
+			if querierInstance.isRetryableError(err) {
+				logger.Warn("querier: request failed, trying the next core", "host", currentHost, "path", path, "error", err)
+				metrics.IncCounter(MetricCoreFailovers, map[string]string{"host": currentHost})
+				return querierInstance.sendRequestHelper(ctx, path, httpRequest, numberOfTries-1)
+			}
+		}
+		logger.Error("querier: request failed", "host", currentHost, "path", path, "error", err)
 		return nil, errors.GeneralError{
 			Msg:         err.Error(),
 			ActualError: err,
+			Host:        currentHost,
+			Path:        path,
+			Reason:      getTransportErrorReason(ctx),
 		}
 	}
 
-	if flag.Lookup("test.v") != nil && !containsHost(hostsAliveForTesting, currentHost) {
-		hostsAliveForTesting = append(hostsAliveForTesting, currentHost)
+	if flag.Lookup("test.v") != nil {
+		hostsAliveForTestingLock.Lock()
+		if !containsHost(hostsAliveForTesting, currentHost) {
+			hostsAliveForTesting = append(hostsAliveForTesting, currentHost)
+		}
+		hostsAliveForTestingLock.Unlock()
 	}
 
 	defer resp.Body.Close()
 
+	var coreResponseError error = nil
 	if resp.StatusCode >= 400 {
-		return nil, errors.GeneralError{
-			Msg:         strconv.Itoa(resp.StatusCode),
-			ActualError: nil,
-		}
+		coreResponseError = getCoreResponseError(resp, currentHost, path)
+	}
+
+	if resp.StatusCode >= 500 {
+		querierInstance.markHostFailure(currentHost, coreResponseError)
+	} else {
+		querierInstance.hostPool.markSuccess(currentHost)
+	}
+
+	if querierInstance.isRetryableStatus(resp.StatusCode) && numberOfTries > 1 {
+		logger.Warn("querier: core responded with an error, trying the next core", "host", currentHost, "path", path,
+			"status", resp.StatusCode)
+		metrics.IncCounter(MetricCoreFailovers, map[string]string{"host": currentHost})
+		return querierInstance.sendRequestHelper(ctx, path, httpRequest, numberOfTries-1)
+	}
+
+	if coreResponseError != nil {
+		logger.Error("querier: core responded with an error", "host", currentHost, "path", path, "status", resp.StatusCode)
+		return nil, coreResponseError
 	}
 
 	var body, readErr = ioutil.ReadAll(resp.Body)
 	if readErr != nil {
+		if ctx.Err() == nil {
+			querierInstance.markHostFailure(currentHost, readErr)
+			if querierInstance.isRetryableError(readErr) {
+				metrics.IncCounter(MetricCoreFailovers, map[string]string{"host": currentHost})
+				return querierInstance.sendRequestHelper(ctx, path, httpRequest, numberOfTries-1)
+			}
+		}
 		return nil, errors.GeneralError{
 			Msg:         readErr.Error(),
 			ActualError: readErr,
+			StatusCode:  resp.StatusCode,
+			Host:        currentHost,
+			Path:        path,
+			Reason:      getTransportErrorReason(ctx),
 		}
 	}
 
@@ -0,0 +1,379 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	goerrors "errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func TestQuerierRespectsContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/apiversion" {
+			w.Write([]byte(`{"versions":["2.3"]}`))
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	instance := NewInstance(server.URL, "", QuerierOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := instance.GetQuerier().SendGetRequestWithContext(ctx, "", "/hello", map[string]string{})
+	if err == nil {
+		t.Error("should have failed")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("request was not aborted when the context deadline passed")
+	}
+}
+
+func TestQuerierDoesNotQueryWithCancelledContext(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	instance := NewInstance(server.URL, "", QuerierOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := instance.GetQuerier().SendGetRequestWithContext(ctx, "", "/hello", map[string]string{})
+	if err == nil {
+		t.Error("should have failed")
+	}
+	if called {
+		t.Error("core should not have been queried")
+	}
+}
+
+func TestQuerierRetriesOn5xxWithRetryPolicy(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/apiversion" {
+			w.Write([]byte(`{"versions":["2.3"]}`))
+			return
+		}
+		calls++
+		if calls < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	defer server.Close()
+
+	instance := NewInstance(server.URL, "", QuerierOptions{
+		RetryPolicy: &RetryPolicy{
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+		},
+	})
+	response, err := instance.GetQuerier().SendGetRequest("", "/hello", map[string]string{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if response["status"] != "OK" || calls != 3 {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierDoesNotRetryOn5xxWithoutRetryPolicy(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/apiversion" {
+			w.Write([]byte(`{"versions":["2.3"]}`))
+			return
+		}
+		calls++
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	instance := NewInstance(server.URL+";"+server.URL, "", QuerierOptions{})
+	_, err := instance.GetQuerier().SendGetRequest("", "/hello", map[string]string{})
+	if err == nil || calls != 1 {
+		t.Error("failed")
+	}
+	var coreResponseError errors.CoreResponseError
+	if !goerrors.As(err, &coreResponseError) || coreResponseError.StatusCode != 500 || coreResponseError.Host != server.URL ||
+		coreResponseError.Path != "/hello" || coreResponseError.Reason != errors.ReasonCoreErrorResponse {
+		t.Error("error does not describe the failed query")
+	}
+}
+
+func TestQuerierFailsOverOnTimeout(t *testing.T) {
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/apiversion" {
+			w.Write([]byte(`{"versions":["2.3"]}`))
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slowServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions":["2.3"],"status":"OK"}`))
+	}))
+	defer server.Close()
+
+	instance := NewInstance(slowServer.URL+";"+server.URL, "", QuerierOptions{
+		RequestTimeout: 100 * time.Millisecond,
+		RetryPolicy:    &RetryPolicy{},
+	})
+	querier := instance.GetQuerier()
+	_, err := querier.GetAPIVersion()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	querier.hostPool.lastTriedIndex = 0
+	response, err := querier.SendGetRequest("", "/hello", map[string]string{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if response["status"] != "OK" {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierFailsOverOnConnectionRefusedWithoutRetryPolicy(t *testing.T) {
+	closedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions":["2.3"],"status":"OK"}`))
+	}))
+	defer server.Close()
+
+	instance := NewInstance(closedServer.URL+";"+server.URL, "", QuerierOptions{})
+	querier := instance.GetQuerier()
+	_, err := querier.GetAPIVersion()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	querier.hostPool.lastTriedIndex = 0
+	response, err := querier.SendGetRequest("", "/hello", map[string]string{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if response["status"] != "OK" || querier.GetHostsStatus()[0].ConsecutiveFailures == 0 {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierClassifiesRetryableErrors(t *testing.T) {
+	withoutPolicy := &querier{}
+	withPolicy := &querier{retryPolicy: &RetryPolicy{}}
+	refused := &url.Error{Op: "Get", URL: "http://localhost:1", Err: &net.OpError{
+		Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+	}}
+	timedOut := &url.Error{Op: "Get", URL: "http://localhost:1", Err: context.DeadlineExceeded}
+	other := goerrors.New("connection refused by a proxy")
+
+	if !withoutPolicy.isRetryableError(refused) || !withPolicy.isRetryableError(refused) {
+		t.Error("refused connections should always be retried")
+	}
+	if withoutPolicy.isRetryableError(timedOut) || !withPolicy.isRetryableError(timedOut) {
+		t.Error("timeouts should only be retried with a retry policy")
+	}
+	if withoutPolicy.isRetryableError(other) || withPolicy.isRetryableError(other) {
+		t.Error("other errors should not be retried")
+	}
+	if withoutPolicy.isRetryableStatus(503) || !withPolicy.isRetryableStatus(503) || withPolicy.isRetryableStatus(400) {
+		t.Error("only 5xx responses should be retried, with a retry policy")
+	}
+}
+
+type countingRoundTripper struct {
+	count int
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestQuerierUsesCustomHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions":["2.3"]}`))
+	}))
+	defer server.Close()
+
+	transport := &countingRoundTripper{}
+	instance := NewInstance(server.URL, "", QuerierOptions{
+		HTTPClient: &http.Client{Transport: transport},
+	})
+	_, err := instance.GetQuerier().SendGetRequest("", "/hello", map[string]string{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if transport.count != 2 {
+		t.Error("custom http client was not used")
+	}
+}
+
+func TestQuerierReturnsCoreResponseErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiversion":
+			w.Write([]byte(`{"versions":["2.3"]}`))
+		case "/unauthorised":
+			w.WriteHeader(401)
+			w.Write([]byte("Invalid API key"))
+		case "/missing":
+			w.WriteHeader(404)
+		default:
+			w.WriteHeader(400)
+			w.Write([]byte("Field name 'userId' is invalid"))
+		}
+	}))
+	defer server.Close()
+	querier := NewInstance(server.URL, "", QuerierOptions{}).GetQuerier()
+
+	_, err := querier.SendGetRequest("", "/unauthorised", map[string]string{})
+	var coreResponseError errors.CoreResponseError
+	if !errors.IsInvalidAPIKeyError(err) || !goerrors.Is(err, errors.ErrCoreResponse) || !goerrors.As(err, &coreResponseError) ||
+		coreResponseError.Body != "Invalid API key" || coreResponseError.CDIVersion != "2.3" {
+		t.Error("failed")
+	}
+
+	_, err = querier.SendGetRequest("", "/missing", map[string]string{})
+	if !errors.IsUnsupportedEndpointError(err) || errors.GetReason(err) != errors.ReasonUnsupportedEndpoint {
+		t.Error("failed")
+	}
+
+	_, err = querier.SendPostRequest("", "/session", map[string]interface{}{})
+	if !goerrors.As(err, &coreResponseError) || coreResponseError.StatusCode != 400 ||
+		coreResponseError.Body != "Field name 'userId' is invalid" || errors.IsInvalidAPIKeyError(err) {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierSharesAPIVersionQueryWithoutBlockingDeadlines(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Write([]byte(`{"versions":["2.3"]}`))
+	}))
+	defer server.Close()
+	instance := NewInstance(server.URL, "", QuerierOptions{})
+	querier := instance.GetQuerier()
+
+	results := make(chan error)
+	go func() {
+		_, err := querier.GetAPIVersion()
+		results <- err
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := querier.GetAPIVersionWithContext(ctx)
+	if errors.GetReason(err) != errors.ReasonContextDone || time.Since(start) > time.Second {
+		t.Error("waiting for the query of another caller should stop at the deadline")
+	}
+
+	go func() {
+		_, err := querier.GetAPIVersion()
+		results <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Error("the api version should have been queried once")
+	}
+}
+
+func TestQuerierRejectsMalformedAPIVersionResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions":"2.3"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewInstance(server.URL, "", QuerierOptions{}).GetQuerier().GetAPIVersion()
+	if errors.GetReason(err) != errors.ReasonCoreIncompatible {
+		t.Error("failed")
+	}
+}
+
+func TestQuerierDoesNotEjectHostsWhenAPIVersionIsRejected(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		body   string
+		reason errors.Reason
+	}{
+		{200, `{"versions":["1.0"]}`, errors.ReasonCoreIncompatible},
+		{401, `Invalid API key`, errors.ReasonInvalidAPIKey},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/apiversion" {
+				t.Error("core should not have been queried: " + r.URL.Path)
+			}
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}))
+		instance := NewInstance(server.URL, "", QuerierOptions{HealthCheck: &HealthCheckOptions{FailureThreshold: 1}})
+		querier := instance.GetQuerier()
+		for i := 0; i < 2; i++ {
+			_, err := querier.SendGetRequestWithContext(context.Background(), "", "/session/user", map[string]string{})
+			if errors.GetReason(err) != test.reason {
+				t.Error("expected " + string(test.reason) + " but got " + string(errors.GetReason(err)))
+			}
+		}
+		for _, status := range querier.hostPool.getStatus() {
+			if !status.Healthy {
+				t.Error("host should not have been ejected")
+			}
+		}
+		server.Close()
+	}
+}
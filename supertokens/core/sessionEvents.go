@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"net/http"
+	"time"
+)
+
+// SessionEventType is the kind of change that happened to a session
+type SessionEventType string
+
+const (
+	// SessionEventCreated is emitted when a new session is created
+	SessionEventCreated SessionEventType = "SESSION_CREATED"
+	// SessionEventRefreshed is emitted when a session is refreshed
+	SessionEventRefreshed SessionEventType = "SESSION_REFRESHED"
+	// SessionEventJWTPayloadUpdated is emitted when the access token of a session is regenerated with a new JWT payload
+	SessionEventJWTPayloadUpdated SessionEventType = "JWT_PAYLOAD_UPDATED"
+	// SessionEventRevoked is emitted for every revoked session
+	SessionEventRevoked SessionEventType = "SESSION_REVOKED"
+	// SessionEventTokenTheftDetected is emitted when a refresh token is reused
+	SessionEventTokenTheftDetected SessionEventType = "TOKEN_THEFT_DETECTED"
+	// SessionEventUnauthorized is emitted when a request's session does not exist or its tokens are missing
+	SessionEventUnauthorized SessionEventType = "UNAUTHORISED"
+)
+
+// SessionEvent describes a change to a session. UserID and SessionHandle are empty when they are not known,
+// for example for unauthorized requests or sessions revoked by their handle
+type SessionEvent struct {
+	Type          SessionEventType
+	UserID        string
+	SessionHandle string
+	// Request that caused the event. It is nil for events not caused by a request
+	Request *http.Request
+	Time    time.Time
+}
+
+type sessionEventHandler struct {
+	handler func(SessionEvent)
+	types   map[SessionEventType]bool
+}
+
+// OnSessionEvent adds a handler for the given types of session events of this instance, or for all of them
+// if no type is given. Handlers are called synchronously, in the order they were added
+func (instance *Instance) OnSessionEvent(handler func(SessionEvent), types ...SessionEventType) {
+	var typesToHandle map[SessionEventType]bool = nil
+	if len(types) != 0 {
+		typesToHandle = map[SessionEventType]bool{}
+		for _, eventType := range types {
+			typesToHandle[eventType] = true
+		}
+	}
+	instance.sessionEventHandlersLock.Lock()
+	defer instance.sessionEventHandlersLock.Unlock()
+	instance.sessionEventHandlers = append(instance.sessionEventHandlers, sessionEventHandler{
+		handler: handler,
+		types:   typesToHandle,
+	})
+}
+
+// EmitSessionEvent counts the event and calls the handlers of this instance that handle the event's type
+func (instance *Instance) EmitSessionEvent(event SessionEvent) {
+	instance.GetMetrics().IncCounter(MetricSessionEvents, map[string]string{"type": string(event.Type)})
+	instance.sessionEventHandlersLock.RLock()
+	handlers := instance.sessionEventHandlers
+	instance.sessionEventHandlersLock.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, handler := range handlers {
+		if handler.types == nil || handler.types[event.Type] {
+			handler.handler(event)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package core
+
+import (
+	"testing"
+)
+
+func TestSessionEventHandlersAreFilteredByType(t *testing.T) {
+	instance := NewInstance("http://localhost:3567", "", QuerierOptions{})
+	var all []SessionEventType
+	var revoked []string
+	instance.OnSessionEvent(func(event SessionEvent) {
+		all = append(all, event.Type)
+	})
+	instance.OnSessionEvent(func(event SessionEvent) {
+		revoked = append(revoked, event.SessionHandle)
+		if event.Time.IsZero() {
+			t.Error("failed")
+		}
+	}, SessionEventRevoked)
+
+	instance.EmitSessionEvent(SessionEvent{Type: SessionEventCreated, SessionHandle: "a"})
+	instance.EmitSessionEvent(SessionEvent{Type: SessionEventRevoked, SessionHandle: "a"})
+
+	if len(all) != 2 || all[0] != SessionEventCreated || all[1] != SessionEventRevoked {
+		t.Error("failed")
+	}
+	if len(revoked) != 1 || revoked[0] != "a" {
+		t.Error("failed")
+	}
+}
@@ -17,6 +17,8 @@
 package core
 
 import (
+	"context"
+
 	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
@@ -51,7 +53,19 @@ type TokenInfo struct {
 // CreateNewSession function used to create a new SuperTokens session
 func CreateNewSession(userID string, jwtPayload map[string]interface{},
 	sessionData map[string]interface{}) (SessionInfo, error) {
-	response, err := GetQuerierInstance().SendPostRequest("newsession", "/session",
+	return defaultInstance.CreateNewSession(context.Background(), userID, jwtPayload, sessionData)
+}
+
+// CreateNewSessionWithContext function used to create a new SuperTokens session, aborting the query to the core when ctx is done
+func CreateNewSessionWithContext(ctx context.Context, userID string, jwtPayload map[string]interface{},
+	sessionData map[string]interface{}) (SessionInfo, error) {
+	return defaultInstance.CreateNewSession(ctx, userID, jwtPayload, sessionData)
+}
+
+// CreateNewSession function used to create a new SuperTokens session, using this instance
+func (instance *Instance) CreateNewSession(ctx context.Context, userID string, jwtPayload map[string]interface{},
+	sessionData map[string]interface{}) (SessionInfo, error) {
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "newsession", "/session",
 		map[string]interface{}{
 			"userId":             userID,
 			"userDataInJWT":      jwtPayload,
@@ -65,22 +79,62 @@ func CreateNewSession(userID string, jwtPayload map[string]interface{},
 
 // GetSession function used to verify a session
 func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool) (SessionInfo, error) {
+	return defaultInstance.GetSession(context.Background(), accessToken, antiCsrfToken, doAntiCsrfCheck)
+}
+
+// GetSessionWithContext function used to verify a session, aborting the query to the core when ctx is done
+func GetSessionWithContext(ctx context.Context, accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool) (SessionInfo, error) {
+	return defaultInstance.GetSession(ctx, accessToken, antiCsrfToken, doAntiCsrfCheck)
+}
+
+// GetSession function used to verify a session, using this instance
+func (instance *Instance) GetSession(ctx context.Context, accessToken string, antiCsrfToken *string,
+	doAntiCsrfCheck bool) (sessionInfo SessionInfo, err error) {
+	ctx, span := instance.GetTracer().StartSpan(ctx, "supertokens.GetSession", SpanKindInternal)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+	// recordVerification tells the metrics and the trace whether the token was verified locally or by the core
+	recordVerification := func(method string) {
+		instance.GetMetrics().IncCounter(MetricSessionVerifications, map[string]string{"method": method})
+		span.SetAttribute("supertokens.verification", method)
+	}
+
+	logger := instance.GetLogger()
+	if verifier := instance.getOfflineVerifier(); verifier != nil {
+		recordVerification("offline")
+		sessionInfo, err := verifier.getSession(accessToken, antiCsrfToken, doAntiCsrfCheck)
+		if err != nil {
+			logger.Debug("getSession: verification with the pinned signing key failed", "error", err)
+		} else {
+			logger.Debug("getSession: verified the access token with the pinned signing key", "sessionHandle", sessionInfo.Handle)
+		}
+		return sessionInfo, err
+	}
 	{
-		handShakeInfo, handShakeError := GetHandshakeInfoInstance()
+		handShakeInfo, handShakeError := instance.GetHandshakeInfo(ctx)
 		if handShakeError != nil {
 			return SessionInfo{}, handShakeError
 		}
-		if handShakeInfo.JwtSigningPublicKeyExpiryTime > getCurrTimeInMS() {
+		jwtSigningPublicKeys := handShakeInfo.getJwtSigningPublicKeysForVerification()
+		if len(jwtSigningPublicKeys) > 0 {
 			accessTokenInfo, accessTokenError := getInfoFromAccessToken(accessToken,
-				handShakeInfo.JwtSigningPublicKey, handShakeInfo.EnableAntiCsrf && doAntiCsrfCheck)
+				jwtSigningPublicKeys, handShakeInfo.EnableAntiCsrf && doAntiCsrfCheck)
 			if accessTokenError == nil {
 				if handShakeInfo.EnableAntiCsrf && doAntiCsrfCheck &&
 					(antiCsrfToken == nil || accessTokenInfo.antiCsrfToken == nil ||
 						*antiCsrfToken != *(accessTokenInfo.antiCsrfToken)) {
+					logger.Debug("getSession: anti-csrf check failed locally, verifying with the core",
+						"sessionHandle", accessTokenInfo.sessionHandle)
 					// we continue querying the core...
 				} else {
 					if !handShakeInfo.AccessTokenBlacklistingEnabled &&
 						accessTokenInfo.parentRefreshTokenHash1 == nil {
+						logger.Debug("getSession: verified the access token locally", "sessionHandle", accessTokenInfo.sessionHandle)
+						recordVerification("local")
 						return SessionInfo{
 							Handle:         accessTokenInfo.sessionHandle,
 							UserID:         accessTokenInfo.userID,
@@ -91,18 +145,27 @@ func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool)
 							AntiCsrfToken:  nil,
 						}, nil
 					}
+					logger.Debug("getSession: the access token must be checked by the core", "sessionHandle", accessTokenInfo.sessionHandle,
+						"blacklisting", handShakeInfo.AccessTokenBlacklistingEnabled,
+						"refreshed", accessTokenInfo.parentRefreshTokenHash1 != nil)
 					// we continue querying the core...
 				}
 			} else {
 				if !errors.IsTryRefreshTokenError(accessTokenError) {
+					logger.Debug("getSession: local verification failed", "error", accessTokenError)
 					return SessionInfo{}, accessTokenError
 				}
+				logger.Debug("getSession: local verification failed, verifying with the core", "error", accessTokenError,
+					"reason", errors.GetReason(accessTokenError))
 				// we continue querying the core...
 			}
+		} else {
+			logger.Debug("getSession: no signing key to verify locally, verifying with the core")
 		}
 	}
 
 	GetProcessStateInstance().AddState(CallingServiceInVerify)
+	recordVerification("core")
 
 	body := map[string]interface{}{
 		"accessToken":     accessToken,
@@ -111,40 +174,57 @@ func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool)
 	if antiCsrfToken != nil {
 		body["antiCsrfToken"] = *antiCsrfToken
 	}
-	response, err := GetQuerierInstance().SendPostRequest("verify", "/session/verify", body)
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "verify", "/session/verify", body)
 	if err != nil {
 		return SessionInfo{}, err
 	}
 	if response["status"] == "OK" {
-		handShakeInfo, handShakeError := GetHandshakeInfoInstance()
+		handShakeInfo, handShakeError := instance.GetHandshakeInfo(ctx)
 		if handShakeError != nil {
-			if err != nil {
-				return SessionInfo{}, handShakeError
-			}
+			return SessionInfo{}, handShakeError
 		}
 		handShakeInfo.UpdateJwtSigningPublicKeyInfo(
 			response["jwtSigningPublicKey"].(string), uint64(response["jwtSigningPublicKeyExpiryTime"].(float64)))
-		return convertJSONResponseToSessionInfo(response), nil
+		sessionInfo := convertJSONResponseToSessionInfo(response)
+		logger.Debug("getSession: verified by the core", "sessionHandle", sessionInfo.Handle,
+			"newAccessToken", sessionInfo.AccessToken != nil)
+		return sessionInfo, nil
 	} else if response["status"] == "UNAUTHORISED" {
+		logger.Debug("getSession: the core says the session does not exist", "message", response["message"])
 		return SessionInfo{}, errors.UnauthorizedError{
-			Msg: response["message"].(string),
+			Msg:    response["message"].(string),
+			Path:   "/session/verify",
+			Reason: errors.ReasonUnauthorisedByCore,
 		}
 	} else {
+		logger.Debug("getSession: the core says the access token must be refreshed", "message", response["message"])
 		return SessionInfo{}, errors.TryRefreshTokenError{
-			Msg: response["message"].(string),
+			Msg:    response["message"].(string),
+			Path:   "/session/verify",
+			Reason: errors.ReasonTryRefreshTokenByCore,
 		}
 	}
 }
 
 // RefreshSession function used to refresh a session
 func RefreshSession(refreshToken string, antiCsrfToken *string) (SessionInfo, error) {
+	return defaultInstance.RefreshSession(context.Background(), refreshToken, antiCsrfToken)
+}
+
+// RefreshSessionWithContext function used to refresh a session, aborting the query to the core when ctx is done
+func RefreshSessionWithContext(ctx context.Context, refreshToken string, antiCsrfToken *string) (SessionInfo, error) {
+	return defaultInstance.RefreshSession(ctx, refreshToken, antiCsrfToken)
+}
+
+// RefreshSession function used to refresh a session, using this instance
+func (instance *Instance) RefreshSession(ctx context.Context, refreshToken string, antiCsrfToken *string) (SessionInfo, error) {
 	body := map[string]interface{}{
 		"refreshToken": refreshToken,
 	}
 	if antiCsrfToken != nil {
 		body["antiCsrfToken"] = *antiCsrfToken
 	}
-	response, err := GetQuerierInstance().SendPostRequest("refresh", "/session/refresh", body)
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "refresh", "/session/refresh", body)
 	if err != nil {
 		return SessionInfo{}, err
 	}
@@ -152,20 +232,34 @@ func RefreshSession(refreshToken string, antiCsrfToken *string) (SessionInfo, er
 		return convertJSONResponseToSessionInfo(response), nil
 	} else if response["status"] == "UNAUTHORISED" {
 		return SessionInfo{}, errors.UnauthorizedError{
-			Msg: response["message"].(string),
+			Msg:    response["message"].(string),
+			Path:   "/session/refresh",
+			Reason: errors.ReasonUnauthorisedByCore,
 		}
 	} else {
 		return SessionInfo{}, errors.TokenTheftDetectedError{
 			Msg:           "Token theft detected",
 			SessionHandle: (response["session"].(map[string]interface{}))["handle"].(string),
 			UserID:        (response["session"].(map[string]interface{}))["userId"].(string),
+			Path:          "/session/refresh",
+			Reason:        errors.ReasonTokenTheftDetected,
 		}
 	}
 }
 
 // RevokeAllSessionsForUser function used to revoke all sessions for a user
 func RevokeAllSessionsForUser(userID string) ([]string, error) {
-	response, err := GetQuerierInstance().SendPostRequest("revokeall", "/session/remove",
+	return defaultInstance.RevokeAllSessionsForUser(context.Background(), userID)
+}
+
+// RevokeAllSessionsForUserWithContext function used to revoke all sessions for a user, aborting the query to the core when ctx is done
+func RevokeAllSessionsForUserWithContext(ctx context.Context, userID string) ([]string, error) {
+	return defaultInstance.RevokeAllSessionsForUser(ctx, userID)
+}
+
+// RevokeAllSessionsForUser function used to revoke all sessions for a user, using this instance
+func (instance *Instance) RevokeAllSessionsForUser(ctx context.Context, userID string) ([]string, error) {
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "revokeall", "/session/remove",
 		map[string]interface{}{
 			"userId": userID,
 		})
@@ -178,7 +272,17 @@ func RevokeAllSessionsForUser(userID string) ([]string, error) {
 
 // GetAllSessionHandlesForUser function used to get all sessions for a user
 func GetAllSessionHandlesForUser(userID string) ([]string, error) {
-	response, err := GetQuerierInstance().SendGetRequest("getall", "/session/user",
+	return defaultInstance.GetAllSessionHandlesForUser(context.Background(), userID)
+}
+
+// GetAllSessionHandlesForUserWithContext function used to get all sessions for a user, aborting the query to the core when ctx is done
+func GetAllSessionHandlesForUserWithContext(ctx context.Context, userID string) ([]string, error) {
+	return defaultInstance.GetAllSessionHandlesForUser(ctx, userID)
+}
+
+// GetAllSessionHandlesForUser function used to get all sessions for a user, using this instance
+func (instance *Instance) GetAllSessionHandlesForUser(ctx context.Context, userID string) ([]string, error) {
+	response, err := instance.GetQuerier().SendGetRequestWithContext(ctx, "getall", "/session/user",
 		map[string]string{
 			"userId": userID,
 		})
@@ -191,7 +295,17 @@ func GetAllSessionHandlesForUser(userID string) ([]string, error) {
 
 // RevokeSession function used to revoke a specific session
 func RevokeSession(sessionHandle string) (bool, error) {
-	response, err := GetQuerierInstance().SendPostRequest("revoke", "/session/remove",
+	return defaultInstance.RevokeSession(context.Background(), sessionHandle)
+}
+
+// RevokeSessionWithContext function used to revoke a specific session, aborting the query to the core when ctx is done
+func RevokeSessionWithContext(ctx context.Context, sessionHandle string) (bool, error) {
+	return defaultInstance.RevokeSession(ctx, sessionHandle)
+}
+
+// RevokeSession function used to revoke a specific session, using this instance
+func (instance *Instance) RevokeSession(ctx context.Context, sessionHandle string) (bool, error) {
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "revoke", "/session/remove",
 		map[string]interface{}{
 			"sessionHandles": [1]string{sessionHandle},
 		})
@@ -203,7 +317,17 @@ func RevokeSession(sessionHandle string) (bool, error) {
 
 // RevokeMultipleSessions function used to revoke a list of sessions
 func RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
-	response, err := GetQuerierInstance().SendPostRequest("revokemultiple", "/session/remove",
+	return defaultInstance.RevokeMultipleSessions(context.Background(), sessionHandles)
+}
+
+// RevokeMultipleSessionsWithContext function used to revoke a list of sessions, aborting the query to the core when ctx is done
+func RevokeMultipleSessionsWithContext(ctx context.Context, sessionHandles []string) ([]string, error) {
+	return defaultInstance.RevokeMultipleSessions(ctx, sessionHandles)
+}
+
+// RevokeMultipleSessions function used to revoke a list of sessions, using this instance
+func (instance *Instance) RevokeMultipleSessions(ctx context.Context, sessionHandles []string) ([]string, error) {
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "revokemultiple", "/session/remove",
 		map[string]interface{}{
 			"sessionHandles": sessionHandles,
 		})
@@ -216,7 +340,17 @@ func RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
 
 // GetSessionData function used to get session data for the given handle
 func GetSessionData(sessionHandle string) (map[string]interface{}, error) {
-	response, err := GetQuerierInstance().SendGetRequest("getsessiondata", "/session/data",
+	return defaultInstance.GetSessionData(context.Background(), sessionHandle)
+}
+
+// GetSessionDataWithContext function used to get session data for the given handle, aborting the query to the core when ctx is done
+func GetSessionDataWithContext(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	return defaultInstance.GetSessionData(ctx, sessionHandle)
+}
+
+// GetSessionData function used to get session data for the given handle, using this instance
+func (instance *Instance) GetSessionData(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	response, err := instance.GetQuerier().SendGetRequestWithContext(ctx, "getsessiondata", "/session/data",
 		map[string]string{
 			"sessionHandle": sessionHandle,
 		})
@@ -227,13 +361,25 @@ func GetSessionData(sessionHandle string) (map[string]interface{}, error) {
 		return response["userDataInDatabase"].(map[string]interface{}), nil
 	}
 	return nil, errors.UnauthorizedError{
-		Msg: response["message"].(string),
+		Msg:    response["message"].(string),
+		Path:   "/session/data",
+		Reason: errors.ReasonUnauthorisedByCore,
 	}
 }
 
 // UpdateSessionData function used to update session data for the given handle
 func UpdateSessionData(sessionHandle string, newSessionData map[string]interface{}) error {
-	response, err := GetQuerierInstance().SendPutRequest("updatesessiondata", "/session/data",
+	return defaultInstance.UpdateSessionData(context.Background(), sessionHandle, newSessionData)
+}
+
+// UpdateSessionDataWithContext function used to update session data for the given handle, aborting the query to the core when ctx is done
+func UpdateSessionDataWithContext(ctx context.Context, sessionHandle string, newSessionData map[string]interface{}) error {
+	return defaultInstance.UpdateSessionData(ctx, sessionHandle, newSessionData)
+}
+
+// UpdateSessionData function used to update session data for the given handle, using this instance
+func (instance *Instance) UpdateSessionData(ctx context.Context, sessionHandle string, newSessionData map[string]interface{}) error {
+	response, err := instance.GetQuerier().SendPutRequestWithContext(ctx, "updatesessiondata", "/session/data",
 		map[string]interface{}{
 			"sessionHandle":      sessionHandle,
 			"userDataInDatabase": newSessionData,
@@ -243,7 +389,9 @@ func UpdateSessionData(sessionHandle string, newSessionData map[string]interface
 	}
 	if response["status"] == "UNAUTHORISED" {
 		return errors.UnauthorizedError{
-			Msg: response["message"].(string),
+			Msg:    response["message"].(string),
+			Path:   "/session/data",
+			Reason: errors.ReasonUnauthorisedByCore,
 		}
 	}
 	return nil
@@ -251,7 +399,17 @@ func UpdateSessionData(sessionHandle string, newSessionData map[string]interface
 
 // GetJWTPayload function used to get jwt payload for the given handle
 func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
-	response, err := GetQuerierInstance().SendGetRequest("getjwtpayload", "/jwt/data",
+	return defaultInstance.GetJWTPayload(context.Background(), sessionHandle)
+}
+
+// GetJWTPayloadWithContext function used to get jwt payload for the given handle, aborting the query to the core when ctx is done
+func GetJWTPayloadWithContext(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	return defaultInstance.GetJWTPayload(ctx, sessionHandle)
+}
+
+// GetJWTPayload function used to get jwt payload for the given handle, using this instance
+func (instance *Instance) GetJWTPayload(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	response, err := instance.GetQuerier().SendGetRequestWithContext(ctx, "getjwtpayload", "/jwt/data",
 		map[string]string{
 			"sessionHandle": sessionHandle,
 		})
@@ -262,13 +420,25 @@ func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
 		return response["userDataInJWT"].(map[string]interface{}), nil
 	}
 	return nil, errors.UnauthorizedError{
-		Msg: response["message"].(string),
+		Msg:    response["message"].(string),
+		Path:   "/jwt/data",
+		Reason: errors.ReasonUnauthorisedByCore,
 	}
 }
 
 // UpdateJWTPayload function used to update jwt payload for the given handle
 func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}) error {
-	response, err := GetQuerierInstance().SendPutRequest("updatejwtpayload", "/jwt/data",
+	return defaultInstance.UpdateJWTPayload(context.Background(), sessionHandle, newJWTPayload)
+}
+
+// UpdateJWTPayloadWithContext function used to update jwt payload for the given handle, aborting the query to the core when ctx is done
+func UpdateJWTPayloadWithContext(ctx context.Context, sessionHandle string, newJWTPayload map[string]interface{}) error {
+	return defaultInstance.UpdateJWTPayload(ctx, sessionHandle, newJWTPayload)
+}
+
+// UpdateJWTPayload function used to update jwt payload for the given handle, using this instance
+func (instance *Instance) UpdateJWTPayload(ctx context.Context, sessionHandle string, newJWTPayload map[string]interface{}) error {
+	response, err := instance.GetQuerier().SendPutRequestWithContext(ctx, "updatejwtpayload", "/jwt/data",
 		map[string]interface{}{
 			"sessionHandle": sessionHandle,
 			"userDataInJWT": newJWTPayload,
@@ -278,7 +448,9 @@ func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}
 	}
 	if response["status"] == "UNAUTHORISED" {
 		return errors.UnauthorizedError{
-			Msg: response["message"].(string),
+			Msg:    response["message"].(string),
+			Path:   "/jwt/data",
+			Reason: errors.ReasonUnauthorisedByCore,
 		}
 	}
 	return nil
@@ -286,7 +458,17 @@ func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}
 
 // RegenerateSession function used to regenerate a session
 func RegenerateSession(accessToken string, newJWTPayload map[string]interface{}) (SessionInfo, error) {
-	response, err := GetQuerierInstance().SendPostRequest("regenerate", "/session/regenerate",
+	return defaultInstance.RegenerateSession(context.Background(), accessToken, newJWTPayload)
+}
+
+// RegenerateSessionWithContext function used to regenerate a session, aborting the query to the core when ctx is done
+func RegenerateSessionWithContext(ctx context.Context, accessToken string, newJWTPayload map[string]interface{}) (SessionInfo, error) {
+	return defaultInstance.RegenerateSession(ctx, accessToken, newJWTPayload)
+}
+
+// RegenerateSession function used to regenerate a session, using this instance
+func (instance *Instance) RegenerateSession(ctx context.Context, accessToken string, newJWTPayload map[string]interface{}) (SessionInfo, error) {
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "regenerate", "/session/regenerate",
 		map[string]interface{}{
 			"accessToken":   accessToken,
 			"userDataInJWT": newJWTPayload,
@@ -296,7 +478,9 @@ func RegenerateSession(accessToken string, newJWTPayload map[string]interface{})
 	}
 	if response["status"] == "UNAUTHORISED" {
 		return SessionInfo{}, errors.UnauthorizedError{
-			Msg: response["message"].(string),
+			Msg:    response["message"].(string),
+			Path:   "/session/regenerate",
+			Reason: errors.ReasonUnauthorisedByCore,
 		}
 	}
 	return convertJSONResponseToSessionInfo(response), nil
@@ -0,0 +1,188 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// SigningKeyOptions configures how the JWT signing public key is kept up to date
+type SigningKeyOptions struct {
+	// RefreshInBackground fetches the new signing key from the core before the current one expires
+	RefreshInBackground bool
+	// RefreshBefore is how long before the key expires it is refreshed. Defaults to 1 minute
+	RefreshBefore time.Duration
+	// MinRefreshInterval is the least time between two background refreshes. Defaults to 10 seconds
+	MinRefreshInterval time.Duration
+	// GracePeriod is how long a rotated out key is still used to verify access tokens. Rotated out keys are
+	// not accepted unless it is set
+	GracePeriod time.Duration
+}
+
+// SigningKeyRotation describes a change of the JWT signing public key
+type SigningKeyRotation struct {
+	PreviousKey      string
+	NewKey           string
+	NewKeyExpiryTime uint64
+	RotatedAt        time.Time
+}
+
+const defaultSigningKeyRefreshBefore = time.Minute
+const defaultSigningKeyMinRefreshInterval = 10 * time.Second
+
+// maxSigningKeyRefreshBackoff bounds how long the background refresh waits after consecutive failures
+const maxSigningKeyRefreshBackoff = 5 * time.Minute
+
+type previousJwtSigningPublicKey struct {
+	key        string
+	validUntil uint64
+}
+
+type signingKeyState struct {
+	options          SigningKeyOptions
+	onRotation       func(SigningKeyRotation)
+	refresherStarted bool
+}
+
+// SetSigningKeyOptions configures the refreshing and rotation of the JWT signing key of this instance
+func (instance *Instance) SetSigningKeyOptions(options SigningKeyOptions) {
+	if options.RefreshBefore <= 0 {
+		options.RefreshBefore = defaultSigningKeyRefreshBefore
+	}
+	if options.MinRefreshInterval <= 0 {
+		options.MinRefreshInterval = defaultSigningKeyMinRefreshInterval
+	}
+	instance.signingKeyLock.Lock()
+	instance.signingKey.options = options
+	instance.signingKeyLock.Unlock()
+
+	instance.handshakeInfoLock.RLock()
+	hasHandshakeInfo := instance.handshakeInfo != nil
+	instance.handshakeInfoLock.RUnlock()
+	if hasHandshakeInfo {
+		instance.startSigningKeyRefresher()
+	}
+}
+
+// OnSigningKeyRotation sets a function that is called whenever the JWT signing key changes
+func (instance *Instance) OnSigningKeyRotation(handler func(SigningKeyRotation)) {
+	instance.signingKeyLock.Lock()
+	defer instance.signingKeyLock.Unlock()
+	instance.signingKey.onRotation = handler
+}
+
+func (instance *Instance) getSigningKeyOptions() SigningKeyOptions {
+	instance.signingKeyLock.RLock()
+	defer instance.signingKeyLock.RUnlock()
+	return instance.signingKey.options
+}
+
+func (instance *Instance) emitSigningKeyRotation(rotation SigningKeyRotation) {
+	instance.signingKeyLock.RLock()
+	handler := instance.signingKey.onRotation
+	instance.signingKeyLock.RUnlock()
+	if handler != nil {
+		handler(rotation)
+	}
+}
+
+// startSigningKeyRefresher starts refreshing the signing key in the background, if enabled.
+// Only one refresher runs per instance, until the instance is closed
+func (instance *Instance) startSigningKeyRefresher() {
+	instance.signingKeyLock.Lock()
+	defer instance.signingKeyLock.Unlock()
+	if !instance.signingKey.options.RefreshInBackground || instance.signingKey.refresherStarted {
+		return
+	}
+	instance.signingKey.refresherStarted = true
+	go instance.runSigningKeyRefresher(instance.signingKey.options)
+}
+
+// runSigningKeyRefresher refreshes the signing key until the instance is closed. After a failed refresh it waits
+// twice as long as after the previous one, up to maxSigningKeyRefreshBackoff, so that a failing core is not hammered
+func (instance *Instance) runSigningKeyRefresher(options SigningKeyOptions) {
+	backoff := time.Duration(0)
+	for {
+		wait := instance.getSigningKeyRefreshWait(options)
+		if backoff > wait {
+			wait = backoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-instance.closed:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), options.MinRefreshInterval)
+		err := instance.refreshSigningKey(ctx)
+		cancel()
+		if err == nil {
+			backoff = 0
+			continue
+		}
+		backoff = getNextSigningKeyRefreshBackoff(backoff, options)
+		instance.GetLogger().Warn("signing key: background refresh failed, the current key is kept",
+			"error", err, "retryIn", backoff)
+	}
+}
+
+func getNextSigningKeyRefreshBackoff(backoff time.Duration, options SigningKeyOptions) time.Duration {
+	if backoff == 0 {
+		return options.MinRefreshInterval
+	}
+	backoff = backoff * 2
+	if backoff > maxSigningKeyRefreshBackoff {
+		return maxSigningKeyRefreshBackoff
+	}
+	return backoff
+}
+
+// getSigningKeyRefreshWait returns how long to wait before the key should be refreshed
+func (instance *Instance) getSigningKeyRefreshWait(options SigningKeyOptions) time.Duration {
+	instance.handshakeInfoLock.RLock()
+	info := instance.handshakeInfo
+	instance.handshakeInfoLock.RUnlock()
+	if info == nil {
+		return options.MinRefreshInterval
+	}
+	refreshAt := time.Unix(0, int64(info.JwtSigningPublicKeyExpiryTime)*int64(time.Millisecond)).Add(-options.RefreshBefore)
+	wait := time.Until(refreshAt)
+	if wait < options.MinRefreshInterval {
+		return options.MinRefreshInterval
+	}
+	return wait
+}
+
+// refreshSigningKey fetches the current signing key from the core and caches it if it changed
+func (instance *Instance) refreshSigningKey(ctx context.Context) error {
+	instance.handshakeInfoLock.RLock()
+	info := instance.handshakeInfo
+	instance.handshakeInfoLock.RUnlock()
+	if info == nil {
+		_, err := instance.GetHandshakeInfo(ctx)
+		return err
+	}
+	response, err := instance.GetQuerier().SendPostRequestWithContext(ctx, "handshake", "/handshake", map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	info.UpdateJwtSigningPublicKeyInfo(response["jwtSigningPublicKey"].(string),
+		uint64(response["jwtSigningPublicKeyExpiryTime"].(float64)))
+	return nil
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func localAccessTokenPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"sessionHandle":     "handle",
+		"userId":            "userId",
+		"refreshTokenHash1": "hash",
+		"userData":          map[string]interface{}{},
+		"expiryTime":        getCurrTimeInMS() + 3600000,
+		"timeCreated":       getCurrTimeInMS(),
+	}
+}
+
+func TestGetSessionAcceptsPreviousKeyDuringGracePeriod(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+	instance.SetSigningKeyOptions(SigningKeyOptions{GracePeriod: time.Hour})
+	var rotations []SigningKeyRotation
+	instance.OnSigningKeyRotation(func(rotation SigningKeyRotation) {
+		rotations = append(rotations, rotation)
+	})
+
+	info, err := instance.GetHandshakeInfo(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	oldKey := info.JwtSigningPublicKey
+	oldToken := c.createAccessToken(t, localAccessTokenPayload())
+	c.rotateKey(t)
+	info.UpdateJwtSigningPublicKeyInfo(c.getPublicKey(), getCurrTimeInMS()+3600000)
+
+	if len(rotations) != 1 || rotations[0].PreviousKey != oldKey || rotations[0].NewKey != c.getPublicKey() {
+		t.Error("rotation was not emitted")
+	}
+	if _, err := instance.GetSession(context.Background(), oldToken, nil, false); err != nil {
+		t.Error(err)
+	}
+	if _, err := instance.GetSession(context.Background(), c.createAccessToken(t, localAccessTokenPayload()), nil, false); err != nil {
+		t.Error(err)
+	}
+	if c.getVerifyCalls() != 0 {
+		t.Error("session should have been verified locally")
+	}
+}
+
+func TestGetSessionQueriesCoreForPreviousKeyWithoutGracePeriod(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+
+	info, err := instance.GetHandshakeInfo(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	oldToken := c.createAccessToken(t, localAccessTokenPayload())
+	c.rotateKey(t)
+	info.UpdateJwtSigningPublicKeyInfo(c.getPublicKey(), getCurrTimeInMS()+3600000)
+
+	if _, err := instance.GetSession(context.Background(), oldToken, nil, false); err != nil {
+		t.Error(err)
+	}
+	if c.getVerifyCalls() != 1 {
+		t.Error("core should have been queried")
+	}
+}
+
+func TestSigningKeyIsRefreshedInBackground(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+	defer instance.Close()
+	rotations := make(chan SigningKeyRotation, 1)
+	instance.OnSigningKeyRotation(func(rotation SigningKeyRotation) {
+		rotations <- rotation
+	})
+	instance.SetSigningKeyOptions(SigningKeyOptions{
+		RefreshInBackground: true,
+		RefreshBefore:       2 * time.Hour,
+		MinRefreshInterval:  20 * time.Millisecond,
+	})
+
+	if _, err := instance.GetHandshakeInfo(context.Background()); err != nil {
+		t.Error(err)
+		return
+	}
+	c.rotateKey(t)
+
+	select {
+	case rotation := <-rotations:
+		if rotation.NewKey != c.getPublicKey() {
+			t.Error("failed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("new signing key was not fetched in the background")
+		return
+	}
+	info, _ := instance.GetHandshakeInfo(context.Background())
+	if info.JwtSigningPublicKey != c.getPublicKey() {
+		t.Error("failed")
+	}
+}
+
+func TestSigningKeyRefreshFailuresAreLoggedWithBackoff(t *testing.T) {
+	c := newTestCore(t)
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+	defer instance.Close()
+	logger := &recordingLogger{}
+	instance.SetLogger(logger)
+	instance.SetSigningKeyOptions(SigningKeyOptions{
+		RefreshInBackground: true,
+		RefreshBefore:       2 * time.Hour,
+		MinRefreshInterval:  20 * time.Millisecond,
+	})
+	if _, err := instance.GetHandshakeInfo(context.Background()); err != nil {
+		t.Error(err)
+		return
+	}
+	c.server.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !logger.contains("signing key: background refresh failed, the current key is kept") {
+		if time.Now().After(deadline) {
+			t.Error("failed refresh was not logged")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	options := SigningKeyOptions{MinRefreshInterval: time.Second}
+	backoff := getNextSigningKeyRefreshBackoff(0, options)
+	if backoff != time.Second {
+		t.Error("failed")
+	}
+	if backoff = getNextSigningKeyRefreshBackoff(backoff, options); backoff != 2*time.Second {
+		t.Error("failed")
+	}
+	if getNextSigningKeyRefreshBackoff(4*time.Minute, options) != maxSigningKeyRefreshBackoff {
+		t.Error("failed")
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package core
+
+import (
+	"context"
+	"net/http"
+)
+
+// SpanKind tells if a span is a call to another service or work inside the SDK
+type SpanKind int
+
+const (
+	// SpanKindInternal is work done inside the SDK, like verifying a session
+	SpanKindInternal SpanKind = iota
+	// SpanKindClient is a query to a core
+	SpanKindClient
+)
+
+// Tracer starts spans around the work of the SDK. It must be safe for concurrent use
+type Tracer interface {
+	// StartSpan starts a span as a child of the span in ctx, if any, and returns a context containing the new span
+	StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, Span)
+	// Inject adds the trace context of ctx to the headers of a query to a core
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is one traced operation
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+func (noopTracer) Inject(ctx context.Context, header http.Header) {}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// SetTracer sets the tracer of this instance. With nil, nothing is traced
+func (instance *Instance) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	instance.tracerLock.Lock()
+	defer instance.tracerLock.Unlock()
+	instance.tracer = tracer
+}
+
+// GetTracer returns the tracer of this instance
+func (instance *Instance) GetTracer() Tracer {
+	instance.tracerLock.RLock()
+	defer instance.tracerLock.RUnlock()
+	if instance.tracer == nil {
+		return noopTracer{}
+	}
+	return instance.tracer
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type testSpanContextKey struct{}
+
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	name       string
+	kind       SpanKind
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (tr *recordingTracer) StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, Span) {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	span := &recordingSpan{name: name, kind: kind, attributes: map[string]interface{}{}}
+	tr.spans = append(tr.spans, span)
+	return context.WithValue(ctx, testSpanContextKey{}, name), span
+}
+
+func (tr *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	if name, ok := ctx.Value(testSpanContextKey{}).(string); ok {
+		header.Set("traceparent", name)
+	}
+}
+
+func (tr *recordingTracer) getSpan(name string) *recordingSpan {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	for _, span := range tr.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *recordingSpan) RecordError(err error)                      { s.attributes["error"] = err }
+func (s *recordingSpan) End()                                       { s.ended = true }
+
+func TestGetSessionIsTraced(t *testing.T) {
+	c := newTestCore(t)
+	defer c.server.Close()
+	var traceparent string
+	server := c.server.Config.Handler
+	c.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/session/verify" {
+			traceparent = r.Header.Get("traceparent")
+		}
+		server.ServeHTTP(w, r)
+	})
+	instance := NewInstance(c.server.URL, "", QuerierOptions{})
+	tracer := &recordingTracer{}
+	instance.SetTracer(tracer)
+
+	payload := map[string]interface{}{
+		"sessionHandle":           "handle",
+		"userId":                  "userId",
+		"refreshTokenHash1":       "hash",
+		"parentRefreshTokenHash1": "parentHash",
+		"userData":                map[string]interface{}{},
+		"expiryTime":              getCurrTimeInMS() + 3600000,
+		"timeCreated":             getCurrTimeInMS(),
+	}
+	if _, err := instance.GetSession(context.Background(), c.createAccessToken(t, payload), nil, false); err != nil {
+		t.Error(err)
+	}
+
+	getSessionSpan := tracer.getSpan("supertokens.GetSession")
+	if getSessionSpan == nil || !getSessionSpan.ended || getSessionSpan.kind != SpanKindInternal ||
+		getSessionSpan.attributes["supertokens.verification"] != "core" {
+		t.Error("failed")
+	}
+	verifySpan := tracer.getSpan("supertokens.core/session/verify")
+	if verifySpan == nil || !verifySpan.ended || verifySpan.kind != SpanKindClient ||
+		verifySpan.attributes["server.address"] != c.server.URL ||
+		verifySpan.attributes["http.response.status_code"] != 200 ||
+		verifySpan.attributes["supertokens.cdi_version"] != "2.3" ||
+		verifySpan.attributes["supertokens.retry_count"] != 0 {
+		t.Error("failed")
+	}
+	if traceparent != "supertokens.core/session/verify" {
+		t.Error("failed")
+	}
+}
@@ -16,58 +16,366 @@
 
 package errors
 
-import "reflect"
+import (
+	goerrors "errors"
+)
+
+// Reason is a machine readable code describing why an error happened
+type Reason string
+
+const (
+	// ReasonCoreUnreachable means no response was received from the core
+	ReasonCoreUnreachable Reason = "CORE_UNREACHABLE"
+	// ReasonCoreErrorResponse means the core responded with a 4xx or 5xx status
+	ReasonCoreErrorResponse Reason = "CORE_ERROR_RESPONSE"
+	// ReasonInvalidAPIKey means the core responded with a 401 because the API key is missing or wrong
+	ReasonInvalidAPIKey Reason = "INVALID_API_KEY"
+	// ReasonUnsupportedEndpoint means the core responded with a 404 because it does not have the queried API
+	ReasonUnsupportedEndpoint Reason = "UNSUPPORTED_ENDPOINT"
+	// ReasonCoreIncompatible means the core does not support any CDI version of this SDK
+	ReasonCoreIncompatible Reason = "CORE_INCOMPATIBLE"
+	// ReasonNoCoreAvailable means no core hosts were configured
+	ReasonNoCoreAvailable Reason = "NO_CORE_AVAILABLE"
+	// ReasonContextDone means the context of the query was cancelled or its deadline passed
+	ReasonContextDone Reason = "CONTEXT_DONE"
+	// ReasonInvalidConfig means the SDK was configured with invalid values
+	ReasonInvalidConfig Reason = "INVALID_CONFIG"
+	// ReasonIDRefreshTokenMissing means the request had no idRefreshToken
+	ReasonIDRefreshTokenMissing Reason = "ID_REFRESH_TOKEN_MISSING"
+	// ReasonAccessTokenMissing means the request had no access token
+	ReasonAccessTokenMissing Reason = "ACCESS_TOKEN_MISSING"
+	// ReasonRefreshTokenMissing means the request had no refresh token
+	ReasonRefreshTokenMissing Reason = "REFRESH_TOKEN_MISSING"
+	// ReasonAccessTokenInvalid means the access token could not be verified
+	ReasonAccessTokenInvalid Reason = "ACCESS_TOKEN_INVALID"
+	// ReasonAccessTokenExpired means the access token has expired
+	ReasonAccessTokenExpired Reason = "ACCESS_TOKEN_EXPIRED"
+	// ReasonAntiCsrfCheckFailed means the anti-csrf token was missing or did not match
+	ReasonAntiCsrfCheckFailed Reason = "ANTI_CSRF_CHECK_FAILED"
+	// ReasonInvalidPayload means a JWT payload or session data could not be encoded, or did not match the type it was decoded into
+	ReasonInvalidPayload Reason = "INVALID_PAYLOAD"
+	// ReasonUnsupportedOption means an option was used that the core does not support
+	ReasonUnsupportedOption Reason = "UNSUPPORTED_OPTION"
+	// ReasonSigningKeyUnavailable means the pinned JWT signing public key could not be loaded
+	ReasonSigningKeyUnavailable Reason = "SIGNING_KEY_UNAVAILABLE"
+	// ReasonUnauthorisedByCore means the core responded that the session does not exist or has been revoked
+	ReasonUnauthorisedByCore Reason = "UNAUTHORISED"
+	// ReasonTryRefreshTokenByCore means the core responded that the session needs to be refreshed
+	ReasonTryRefreshTokenByCore Reason = "TRY_REFRESH_TOKEN"
+	// ReasonTokenTheftDetected means the core detected the use of an old refresh token
+	ReasonTokenTheftDetected Reason = "TOKEN_THEFT_DETECTED"
+	// ReasonInvalidClaim means the JWT payload of a valid session failed a claim validator
+	ReasonInvalidClaim Reason = "INVALID_CLAIM"
+	// ReasonMissingMiddleware means claims were checked on a request that did not go through the session middleware
+	ReasonMissingMiddleware Reason = "MISSING_MIDDLEWARE"
+)
+
+// Sentinel values to be used with errors.Is. Every error of the matching type, wrapped or not, is considered equal to them
+var (
+	ErrGeneral             = goerrors.New("general error")
+	ErrTryRefreshToken     = goerrors.New("try refresh token")
+	ErrTokenTheftDetected  = goerrors.New("token theft detected")
+	ErrUnauthorized        = goerrors.New("unauthorized")
+	ErrOfflineVerification = goerrors.New("offline verification failed")
+	ErrCoreResponse        = goerrors.New("core error response")
+	ErrInvalidAPIKey       = goerrors.New("invalid api key")
+	ErrUnsupportedEndpoint = goerrors.New("unsupported endpoint")
+	ErrInvalidClaim        = goerrors.New("invalid claim")
+)
 
 // GeneralError used for non specific exceptions
 type GeneralError struct {
 	Msg         string
 	ActualError error
+	// StatusCode of the core's response, if the error came from one
+	StatusCode int
+	// Host of the core that was queried, if known
+	Host string
+	// Path of the core API that was queried, if any
+	Path   string
+	Reason Reason
 }
 
 func (err GeneralError) Error() string {
 	return err.Msg
 }
 
+// Unwrap returns the error that caused this one
+func (err GeneralError) Unwrap() error {
+	return err.ActualError
+}
+
+// Is returns true for ErrGeneral
+func (err GeneralError) Is(target error) bool {
+	return target == ErrGeneral
+}
+
 // TryRefreshTokenError used for when the refresh API needs to be called
 type TryRefreshTokenError struct {
-	Msg string
+	Msg         string
+	ActualError error
+	// StatusCode of the core's response, if the error came from one
+	StatusCode int
+	// Host of the core that was queried, if known
+	Host string
+	// Path of the core API that was queried, if any
+	Path   string
+	Reason Reason
 }
 
 func (err TryRefreshTokenError) Error() string {
 	return err.Msg
 }
 
+// Unwrap returns the error that caused this one
+func (err TryRefreshTokenError) Unwrap() error {
+	return err.ActualError
+}
+
+// Is returns true for ErrTryRefreshToken
+func (err TryRefreshTokenError) Is(target error) bool {
+	return target == ErrTryRefreshToken
+}
+
 // TokenTheftDetectedError used for when token theft has happened for a session
 type TokenTheftDetectedError struct {
 	Msg           string
 	SessionHandle string
 	UserID        string
+	ActualError   error
+	// StatusCode of the core's response, if the error came from one
+	StatusCode int
+	// Host of the core that was queried, if known
+	Host string
+	// Path of the core API that was queried, if any
+	Path   string
+	Reason Reason
 }
 
 func (err TokenTheftDetectedError) Error() string {
 	return err.Msg
 }
 
+// Unwrap returns the error that caused this one
+func (err TokenTheftDetectedError) Unwrap() error {
+	return err.ActualError
+}
+
+// Is returns true for ErrTokenTheftDetected
+func (err TokenTheftDetectedError) Is(target error) bool {
+	return target == ErrTokenTheftDetected
+}
+
 // UnauthorizedError used for when the user has been logged out
 type UnauthorizedError struct {
-	Msg string
+	Msg         string
+	ActualError error
+	// StatusCode of the core's response, if the error came from one
+	StatusCode int
+	// Host of the core that was queried, if known
+	Host string
+	// Path of the core API that was queried, if any
+	Path   string
+	Reason Reason
 }
 
 func (err UnauthorizedError) Error() string {
 	return err.Msg
 }
 
-// IsTokenTheftDetectedError returns true if error is a TokenTheftDetectedError
+// Unwrap returns the error that caused this one
+func (err UnauthorizedError) Unwrap() error {
+	return err.ActualError
+}
+
+// Is returns true for ErrUnauthorized
+func (err UnauthorizedError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+// OfflineVerificationError used for when an access token cannot be verified with the pinned JWT signing public key
+type OfflineVerificationError struct {
+	Msg         string
+	ActualError error
+	// StatusCode is always 0 since the core is not queried
+	StatusCode int
+	// Host is always empty since the core is not queried
+	Host string
+	// Path is always empty since the core is not queried
+	Path   string
+	Reason Reason
+}
+
+func (err OfflineVerificationError) Error() string {
+	return err.Msg
+}
+
+// Unwrap returns the error that caused this one
+func (err OfflineVerificationError) Unwrap() error {
+	return err.ActualError
+}
+
+// Is returns true for ErrOfflineVerification
+func (err OfflineVerificationError) Is(target error) bool {
+	return target == ErrOfflineVerification
+}
+
+// CoreResponseError used for when the core responds with a 4xx or 5xx status
+type CoreResponseError struct {
+	Msg        string
+	StatusCode int
+	// Body of the core's response
+	Body string
+	// Host of the core that responded
+	Host string
+	// Path of the core API that was queried
+	Path string
+	// CDIVersion the query was made with. Empty when querying the supported versions
+	CDIVersion string
+	Reason     Reason
+}
+
+func (err CoreResponseError) Error() string {
+	return err.Msg
+}
+
+// Is returns true for ErrCoreResponse
+func (err CoreResponseError) Is(target error) bool {
+	return target == ErrCoreResponse
+}
+
+// InvalidAPIKeyError used for when the core responds with a 401 because the API key is missing or does not match
+type InvalidAPIKeyError struct {
+	CoreResponseError
+}
+
+// Unwrap returns the core's response
+func (err InvalidAPIKeyError) Unwrap() error {
+	return err.CoreResponseError
+}
+
+// Is returns true for ErrInvalidAPIKey
+func (err InvalidAPIKeyError) Is(target error) bool {
+	return target == ErrInvalidAPIKey
+}
+
+// UnsupportedEndpointError used for when the core responds with a 404 because it does not have the queried API
+type UnsupportedEndpointError struct {
+	CoreResponseError
+}
+
+// Unwrap returns the core's response
+func (err UnsupportedEndpointError) Unwrap() error {
+	return err.CoreResponseError
+}
+
+// Is returns true for ErrUnsupportedEndpoint
+func (err UnsupportedEndpointError) Is(target error) bool {
+	return target == ErrUnsupportedEndpoint
+}
+
+// ClaimValidationError is a claim of the JWT payload that failed validation
+type ClaimValidationError struct {
+	// ID of the claim, usually the key of the JWT payload that was checked
+	ID string `json:"id"`
+	// Reason describes why the claim is invalid, for example the expected and actual values
+	Reason map[string]interface{} `json:"reason"`
+}
+
+// InvalidClaimError used for when the JWT payload of a valid session fails claim validation
+type InvalidClaimError struct {
+	Msg                   string
+	ClaimValidationErrors []ClaimValidationError
+	Reason                Reason
+}
+
+func (err InvalidClaimError) Error() string {
+	return err.Msg
+}
+
+// Is returns true for ErrInvalidClaim
+func (err InvalidClaimError) Is(target error) bool {
+	return target == ErrInvalidClaim
+}
+
+// IsTokenTheftDetectedError returns true if error is, or wraps, a TokenTheftDetectedError
 func IsTokenTheftDetectedError(err error) bool {
-	return reflect.TypeOf(err) == reflect.TypeOf(TokenTheftDetectedError{})
+	var target TokenTheftDetectedError
+	return goerrors.As(err, &target)
 }
 
-// IsUnauthorizedError returns true if error is a UnauthorizedError
+// IsUnauthorizedError returns true if error is, or wraps, a UnauthorizedError
 func IsUnauthorizedError(err error) bool {
-	return reflect.TypeOf(err) == reflect.TypeOf(UnauthorizedError{})
+	var target UnauthorizedError
+	return goerrors.As(err, &target)
 }
 
-// IsTryRefreshTokenError returns true if error is a TryRefreshTokenError
+// IsTryRefreshTokenError returns true if error is, or wraps, a TryRefreshTokenError
 func IsTryRefreshTokenError(err error) bool {
-	return reflect.TypeOf(err) == reflect.TypeOf(TryRefreshTokenError{})
+	var target TryRefreshTokenError
+	return goerrors.As(err, &target)
+}
+
+// IsOfflineVerificationError returns true if error is, or wraps, a OfflineVerificationError
+func IsOfflineVerificationError(err error) bool {
+	var target OfflineVerificationError
+	return goerrors.As(err, &target)
+}
+
+// IsGeneralError returns true if error is, or wraps, a GeneralError
+func IsGeneralError(err error) bool {
+	var target GeneralError
+	return goerrors.As(err, &target)
+}
+
+// IsCoreResponseError returns true if error is, or wraps, a CoreResponseError
+func IsCoreResponseError(err error) bool {
+	var target CoreResponseError
+	return goerrors.As(err, &target)
+}
+
+// IsInvalidAPIKeyError returns true if error is, or wraps, a InvalidAPIKeyError
+func IsInvalidAPIKeyError(err error) bool {
+	var target InvalidAPIKeyError
+	return goerrors.As(err, &target)
+}
+
+// IsUnsupportedEndpointError returns true if error is, or wraps, a UnsupportedEndpointError
+func IsUnsupportedEndpointError(err error) bool {
+	var target UnsupportedEndpointError
+	return goerrors.As(err, &target)
+}
+
+// IsInvalidClaimError returns true if error is, or wraps, a InvalidClaimError
+func IsInvalidClaimError(err error) bool {
+	var target InvalidClaimError
+	return goerrors.As(err, &target)
+}
+
+// GetReason returns the reason code of the first SuperTokens error in err's chain, or an empty string
+func GetReason(err error) Reason {
+	for err != nil {
+		switch actual := err.(type) {
+		case GeneralError:
+			return actual.Reason
+		case TryRefreshTokenError:
+			return actual.Reason
+		case TokenTheftDetectedError:
+			return actual.Reason
+		case UnauthorizedError:
+			return actual.Reason
+		case OfflineVerificationError:
+			return actual.Reason
+		case CoreResponseError:
+			return actual.Reason
+		case InvalidAPIKeyError:
+			return actual.Reason
+		case UnsupportedEndpointError:
+			return actual.Reason
+		case InvalidClaimError:
+			return actual.Reason
+		}
+		err = goerrors.Unwrap(err)
+	}
+	return ""
 }
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package errors
+
+import (
+	goerrors "errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsHelpersMatchWrappedErrors(t *testing.T) {
+	err := fmt.Errorf("verifying session: %w", UnauthorizedError{Msg: "session revoked"})
+	if !IsUnauthorizedError(err) || !goerrors.Is(err, ErrUnauthorized) {
+		t.Error("failed")
+	}
+	if IsTryRefreshTokenError(err) || goerrors.Is(err, ErrTryRefreshToken) {
+		t.Error("failed")
+	}
+	var target UnauthorizedError
+	if !goerrors.As(err, &target) || target.Msg != "session revoked" {
+		t.Error("failed")
+	}
+}
+
+func TestGeneralErrorUnwrapsActualError(t *testing.T) {
+	actual := goerrors.New("connection refused")
+	err := GeneralError{Msg: "failed", ActualError: actual}
+	if !goerrors.Is(err, actual) || !goerrors.Is(err, ErrGeneral) {
+		t.Error("failed")
+	}
+}
+
+func TestGetReason(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", OfflineVerificationError{
+		Msg:         "Access token expired",
+		ActualError: TryRefreshTokenError{Msg: "Access token expired", Reason: ReasonAccessTokenExpired},
+		Reason:      ReasonAccessTokenExpired,
+	})
+	if GetReason(err) != ReasonAccessTokenExpired {
+		t.Error("failed")
+	}
+	if !IsTryRefreshTokenError(err) || !IsOfflineVerificationError(err) {
+		t.Error("failed")
+	}
+	if GetReason(goerrors.New("other")) != "" {
+		t.Error("failed")
+	}
+}
@@ -18,78 +18,129 @@ package supertokens
 
 import (
 	"context"
+	goerrors "errors"
 	"net/http"
 
-	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/claims"
 	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
-// Middleware for verifying and refreshing session. ExtraParams are: bool, func(error, http.ResponseWriter)
+// MiddlewareOptions configures MiddlewareWithOptions
+type MiddlewareOptions struct {
+	// AntiCsrf enables or disables the anti-csrf check. By default it is done for every request except GET ones
+	AntiCsrf *bool
+	// ErrorHandler responds to errors verifying or refreshing the session. Defaults to HandleErrorAndRespond
+	ErrorHandler func(err error, w http.ResponseWriter)
+	// SessionRequired defaults to true. If false, requests without session tokens are passed to the handler,
+	// for which GetSessionFromRequest returns nil. Expired or invalid tokens are still answered with an error
+	SessionRequired *bool
+	// OverrideGlobalClaimValidators returns the validators to check the session with instead of the ClaimValidators
+	// of the config, which it is given
+	OverrideGlobalClaimValidators func(globalClaimValidators []claims.Validator) []claims.Validator
+}
+
+// Middleware for verifying and refreshing session. ExtraParams are: bool, func(error, http.ResponseWriter).
+// Prefer MiddlewareWithOptions, which is checked at compile time
 func Middleware(theirHandler http.HandlerFunc, extraParams ...interface{}) http.HandlerFunc {
+	return defaultClient.Middleware(theirHandler, extraParams...)
+}
+
+// Middleware for verifying and refreshing session using this client. ExtraParams are: bool, func(error, http.ResponseWriter).
+// Prefer MiddlewareWithOptions, which is checked at compile time
+func (client *Client) Middleware(theirHandler http.HandlerFunc, extraParams ...interface{}) http.HandlerFunc {
+	options := MiddlewareOptions{}
+	if len(extraParams) != 0 {
+		if antiCsrf, ok := extraParams[0].(bool); ok {
+			options.AntiCsrf = &antiCsrf
+		}
+	}
+	if len(extraParams) == 2 {
+		// a nil error handler uses the default one, as before
+		if errorHandler, ok := extraParams[1].(func(err error, w http.ResponseWriter)); ok {
+			options.ErrorHandler = errorHandler
+		}
+	}
+	return client.MiddlewareWithOptions(theirHandler, options)
+}
+
+// MiddlewareWithOptions for verifying and refreshing session. The session is then read with GetSessionFromRequest
+func MiddlewareWithOptions(theirHandler http.Handler, options MiddlewareOptions) http.HandlerFunc {
+	return defaultClient.MiddlewareWithOptions(theirHandler, options)
+}
+
+// MiddlewareWithOptions for verifying and refreshing session using this client. The session is then read with GetSessionFromRequest
+func (client *Client) MiddlewareWithOptions(theirHandler http.Handler, options MiddlewareOptions) http.HandlerFunc {
+	handleError := options.ErrorHandler
+	if handleError == nil {
+		handleError = client.HandleErrorAndRespond
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "OPTIONS" || r.Method == "TRACE" {
 			theirHandler.ServeHTTP(w, r)
 			return
 		}
-		var path = r.URL.Path
-		handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
-		if handshakeInfoError != nil {
-			if len(extraParams) != 2 {
-				HandleErrorAndRespond(handshakeInfoError, w)
-			} else {
-				extraParams[1].(func(err error, w http.ResponseWriter))(handshakeInfoError, w)
-			}
+		refreshTokenPath, refreshTokenPathError := client.getRefreshAPIPath(r.Context())
+		if refreshTokenPathError != nil {
+			handleError(refreshTokenPathError, w)
 			return
 		}
-		refreshTokenPath := handshakeInfo.RefreshTokenPath
-		if configMap != nil && configMap.RefreshAPIPath != "" {
-			refreshTokenPath = configMap.RefreshAPIPath
-		}
-		if (refreshTokenPath == path ||
-			(refreshTokenPath+"/") == path ||
-			refreshTokenPath == (path+"/")) &&
-			r.Method == "POST" {
-			session, sessionError := RefreshSession(w, r)
+		if isAPIPath(r.URL.Path, refreshTokenPath) && r.Method == "POST" {
+			session, sessionError := client.RefreshSession(w, r)
 			if sessionError != nil {
-				if len(extraParams) != 2 {
-					HandleErrorAndRespond(sessionError, w)
-				} else {
-					extraParams[1].(func(err error, w http.ResponseWriter))(sessionError, w)
-				}
+				handleError(sessionError, w)
 				return
 			}
 			ctx := context.WithValue(r.Context(), sessionContext, session)
 			theirHandler.ServeHTTP(w, r.WithContext(ctx))
 		} else {
 			var actualDoAntiCsrfCheck = r.Method != "GET"
-			if len(extraParams) != 0 && extraParams[0] != nil {
-				actualDoAntiCsrfCheck = extraParams[0].(bool)
+			if options.AntiCsrf != nil {
+				actualDoAntiCsrfCheck = *options.AntiCsrf
+			}
+			var session *Session
+			var sessionError error
+			if options.SessionRequired == nil || *options.SessionRequired {
+				var requiredSession Session
+				requiredSession, sessionError = client.GetSession(w, r, actualDoAntiCsrfCheck)
+				session = &requiredSession
+			} else {
+				session, sessionError = client.GetOptionalSession(w, r, actualDoAntiCsrfCheck)
 			}
-			session, sessionError := GetSession(w, r, actualDoAntiCsrfCheck)
 			if sessionError != nil {
-				if len(extraParams) != 2 {
-					HandleErrorAndRespond(sessionError, w)
-				} else {
-					extraParams[1].(func(err error, w http.ResponseWriter))(sessionError, w)
-				}
+				handleError(sessionError, w)
 				return
 			}
-			ctx := context.WithValue(r.Context(), sessionContext, session)
-			theirHandler.ServeHTTP(w, r.WithContext(ctx))
+			if session != nil {
+				if err := validateClaims(r, session.GetJWTPayload(), client.getClaimValidators(options)); err != nil {
+					handleError(err, w)
+					return
+				}
+				r = r.WithContext(context.WithValue(r.Context(), sessionContext, *session))
+			}
+			theirHandler.ServeHTTP(w, r)
 		}
 	})
 }
 
 // HandleErrorAndRespond if error handlers are provided, then uses those, else does default error handling depending on the type of error
 func HandleErrorAndRespond(err error, w http.ResponseWriter) {
-	errorHandlers := core.GetErrorHandlersInstance()
-	if errors.IsUnauthorizedError(err) {
+	defaultClient.HandleErrorAndRespond(err, w)
+}
+
+// HandleErrorAndRespond if error handlers are provided to this client, then uses those, else does default error handling depending on the type of error
+func (client *Client) HandleErrorAndRespond(err error, w http.ResponseWriter) {
+	errorHandlers := client.instance.GetErrorHandlers()
+	var tokenTheftDetectedError errors.TokenTheftDetectedError
+	if goerrors.As(err, &tokenTheftDetectedError) {
+		errorHandlers.OnTokenTheftDetectedErrorHandler(tokenTheftDetectedError.SessionHandle, tokenTheftDetectedError.UserID, w)
+	} else if errors.IsUnauthorizedError(err) {
 		errorHandlers.OnUnauthorizedErrorHandler(err, w)
 	} else if errors.IsTryRefreshTokenError(err) {
 		errorHandlers.OnTryRefreshTokenErrorHandler(err, w)
-	} else if errors.IsTokenTheftDetectedError(err) {
-		actualError := err.(errors.TokenTheftDetectedError)
-		errorHandlers.OnTokenTheftDetectedErrorHandler(actualError.SessionHandle, actualError.UserID, w)
+	} else if errors.IsOfflineVerificationError(err) {
+		errorHandlers.OnOfflineVerificationErrorHandler(err, w)
+	} else if errors.IsInvalidClaimError(err) {
+		errorHandlers.OnInvalidClaimErrorHandler(err, w)
 	} else {
 		errorHandlers.OnGeneralErrorHandler(err, w)
 	}
@@ -0,0 +1,182 @@
+package supertokens
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+	"github.com/supertokens/supertokens-go/supertokens/supertokenstest"
+)
+
+// newMiddlewareTestRequest creates a session and returns a request with its cookies, without the anti-csrf header
+func newMiddlewareTestRequest(t *testing.T, client *Client, method string, path string) *http.Request {
+	w := httptest.NewRecorder()
+	_, err := client.CreateNewSession(w, "user")
+	assert.NoError(t, err)
+	request := httptest.NewRequest(method, path, nil)
+	for _, cookie := range w.Result().Cookies() {
+		request.AddCookie(cookie)
+	}
+	return request
+}
+
+type sessionUserHandler struct{}
+
+func (sessionUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(GetSessionFromRequest(r).GetUserID()))
+}
+
+func Test_MiddlewareWithOptions_Handler(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	client.MiddlewareWithOptions(sessionUserHandler{}, MiddlewareOptions{}).
+		ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "GET", "/user"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	client.MiddlewareWithOptions(sessionUserHandler{}, MiddlewareOptions{}).
+		ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/refresh"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+	assert.Equal(t, 1, core.RequestCount("/session/refresh"))
+}
+
+func Test_MiddlewareWithOptions_AntiCsrf(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{EnableAntiCsrf: true, AccessTokenBlacklisting: true})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	client.MiddlewareWithOptions(sessionUserHandler{}, MiddlewareOptions{}).
+		ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/user"))
+	assert.Equal(t, 401, recorder.Code, "POST requests are checked by default")
+
+	antiCsrf := false
+	recorder = httptest.NewRecorder()
+	client.MiddlewareWithOptions(sessionUserHandler{}, MiddlewareOptions{AntiCsrf: &antiCsrf}).
+		ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/user"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+}
+
+func Test_MiddlewareWithOptions_ErrorHandler(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	var handledError error
+	recorder := httptest.NewRecorder()
+	client.MiddlewareWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not have been called")
+	}), MiddlewareOptions{
+		ErrorHandler: func(err error, w http.ResponseWriter) {
+			handledError = err
+			w.WriteHeader(418)
+		},
+	}).ServeHTTP(recorder, httptest.NewRequest("GET", "/user", nil))
+	assert.Equal(t, 418, recorder.Code)
+	assert.True(t, errors.IsUnauthorizedError(handledError))
+}
+
+func Test_Middleware_NilExtraParams(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	handler := client.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not have been called")
+	}, nil, nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/user", nil))
+	assert.Equal(t, 401, recorder.Code, "the default error handler should be used")
+}
+
+func Test_MiddlewareWithOptions_OptionalSession(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	sessionRequired := false
+	handler := client.MiddlewareWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if session := GetSessionFromRequest(r); session != nil {
+			w.Write([]byte(session.GetUserID()))
+		} else {
+			w.Write([]byte("anonymous"))
+		}
+	}), MiddlewareOptions{SessionRequired: &sessionRequired})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "anonymous", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "GET", "/"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.AddCookie(&http.Cookie{Name: idRefreshTokenCookieKey, Value: "idRefreshToken"})
+	request.AddCookie(&http.Cookie{Name: accessTokenCookieKey, Value: "tampered"})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, 401, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "try refresh token"))
+}
+
+func Test_Middleware_OfflineVerification_RootPathIsNotRefreshed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("core should not have been queried: " + r.URL.Path)
+	}))
+	defer server.Close()
+	client, err := New(ConfigMap{Hosts: server.URL, JWTSigningPublicKey: "key"})
+	assert.NoError(t, err)
+
+	for _, cookies := range [][]*http.Cookie{
+		nil,
+		{
+			{Name: idRefreshTokenCookieKey, Value: "idRefreshToken"},
+			{Name: accessTokenCookieKey, Value: "accessToken"},
+			{Name: refreshTokenCookieKey, Value: "refreshToken"},
+		},
+	} {
+		request := httptest.NewRequest("POST", "/", nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		client.Middleware(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("handler should not have been called")
+		})(recorder, request)
+		assert.Equal(t, 401, recorder.Code)
+	}
+}
+
+func Test_GetOptionalSession_HeaderTransfer(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL(), TokenTransferMethod: TokenTransferMethodHeader})
+	assert.NoError(t, err)
+
+	session, err := client.GetOptionalSession(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), false)
+	assert.NoError(t, err)
+	assert.Nil(t, session)
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Authorization", "Bearer tampered")
+	session, err = client.GetOptionalSession(httptest.NewRecorder(), request, false)
+	assert.True(t, errors.IsTryRefreshTokenError(err))
+	assert.Nil(t, session)
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// NewSessionOption configures a session created with NewSession. There is no option to override the cookie settings
+// of one session: RefreshSession and clearing the cookies would still use the config, so the browser would be left
+// with two sets of cookies. Use a Client with its own cookie settings instead
+type NewSessionOption func(*newSessionConfig)
+
+// NewSessionResult is a new session along with the details of the tokens that were issued
+type NewSessionResult struct {
+	Session              Session
+	AccessTokenExpiry    time.Time
+	RefreshTokenExpiry   time.Time
+	IDRefreshTokenExpiry time.Time
+	// AntiCsrfToken is empty if anti-csrf is disabled in the core
+	AntiCsrfToken string
+}
+
+type newSessionConfig struct {
+	jwtPayload          interface{}
+	sessionData         interface{}
+	accessTokenLifetime time.Duration
+	tokenTransferMethod string
+	request             *http.Request
+}
+
+// WithJWTPayload sets the JWT payload. It can be anything that encodes to a JSON object
+func WithJWTPayload(payload interface{}) NewSessionOption {
+	return func(config *newSessionConfig) {
+		config.jwtPayload = payload
+	}
+}
+
+// WithSessionData sets the session data stored in the core. It can be anything that encodes to a JSON object
+func WithSessionData(data interface{}) NewSessionOption {
+	return func(config *newSessionConfig) {
+		config.sessionData = data
+	}
+}
+
+// WithAccessTokenLifetime sets the lifetime of the access token of this session.
+// The supported CDI versions do not allow this, so NewSession returns an error when it is used,
+// without querying the core
+func WithAccessTokenLifetime(lifetime time.Duration) NewSessionOption {
+	return func(config *newSessionConfig) {
+		config.accessTokenLifetime = lifetime
+	}
+}
+
+// WithTokenTransfer sends the tokens of this session with the given method instead of the configured TokenTransferMethod
+func WithTokenTransfer(tokenTransferMethod string) NewSessionOption {
+	return func(config *newSessionConfig) {
+		config.tokenTransferMethod = tokenTransferMethod
+	}
+}
+
+// WithRequest sets the request that the session is created for, which is passed on to the SessionCreated event
+func WithRequest(request *http.Request) NewSessionOption {
+	return func(config *newSessionConfig) {
+		config.request = request
+	}
+}
+
+// NewSession function used to create a new SuperTokens session
+func NewSession(response http.ResponseWriter, userID string, options ...NewSessionOption) (NewSessionResult, error) {
+	return defaultClient.NewSession(response, userID, options...)
+}
+
+// NewSessionWithContext function used to create a new SuperTokens session, aborting the query to the core when ctx is done
+func NewSessionWithContext(ctx context.Context, response http.ResponseWriter, userID string, options ...NewSessionOption) (NewSessionResult, error) {
+	return defaultClient.NewSessionWithContext(ctx, response, userID, options...)
+}
+
+// NewSession function used to create a new SuperTokens session
+func (client *Client) NewSession(response http.ResponseWriter, userID string, options ...NewSessionOption) (NewSessionResult, error) {
+	return client.NewSessionWithContext(context.Background(), response, userID, options...)
+}
+
+// NewSessionWithContext function used to create a new SuperTokens session, aborting the query to the core when ctx is done
+func (client *Client) NewSessionWithContext(ctx context.Context, response http.ResponseWriter, userID string, options ...NewSessionOption) (NewSessionResult, error) {
+	config := newSessionConfig{
+		tokenTransferMethod: client.getTokenTransferMethod(),
+	}
+	for _, option := range options {
+		option(&config)
+	}
+
+	if config.tokenTransferMethod != TokenTransferMethodCookie && config.tokenTransferMethod != TokenTransferMethodHeader &&
+		config.tokenTransferMethod != TokenTransferMethodAny {
+		return NewSessionResult{}, errors.GeneralError{
+			Msg:    "The token transfer method must be one of cookie, header or any",
+			Reason: errors.ReasonInvalidConfig,
+		}
+	}
+	if config.accessTokenLifetime != 0 {
+		return NewSessionResult{}, errors.GeneralError{
+			Msg: "The supported SuperTokens cores do not support setting the access token lifetime per session. " +
+				"Please set access_token_validity in the core's config instead",
+			Reason: errors.ReasonUnsupportedOption,
+		}
+	}
+
+	jwtPayload, err := encodePayload("JWT payload", config.jwtPayload)
+	if err != nil {
+		return NewSessionResult{}, err
+	}
+	sessionData, err := encodePayload("session data", config.sessionData)
+	if err != nil {
+		return NewSessionResult{}, err
+	}
+
+	session, sessionInfo, err := client.createNewSession(ctx, response, config.request, userID, jwtPayload, sessionData, config.tokenTransferMethod)
+	if err != nil {
+		return NewSessionResult{}, err
+	}
+	result := NewSessionResult{
+		Session:              session,
+		AccessTokenExpiry:    getTimeFromMS(sessionInfo.AccessToken.Expiry),
+		RefreshTokenExpiry:   getTimeFromMS(sessionInfo.RefreshToken.Expiry),
+		IDRefreshTokenExpiry: getTimeFromMS(sessionInfo.IDRefreshToken.Expiry),
+	}
+	if sessionInfo.AntiCsrfToken != nil {
+		result.AntiCsrfToken = *sessionInfo.AntiCsrfToken
+	}
+	return result, nil
+}
+
+func getTimeFromMS(ms uint64) time.Time {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond))
+}
@@ -0,0 +1,112 @@
+package supertokens
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func newSessionTestCore(t *testing.T, requestBody *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiversion":
+			w.Write([]byte(`{"versions":["2.3"]}`))
+		case "/session":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(requestBody))
+			token := func(name string, expiry int64) map[string]interface{} {
+				return map[string]interface{}{
+					"token": name, "expiry": expiry, "createdTime": 0,
+					"cookiePath": "/", "cookieSecure": false, "domain": "core.example.com", "sameSite": "lax",
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status": "OK",
+				"session": map[string]interface{}{
+					"handle": "handle", "userId": "user", "userDataInJWT": (*requestBody)["userDataInJWT"],
+				},
+				"accessToken":    token("access", 1000),
+				"refreshToken":   token("refresh", 2000),
+				"idRefreshToken": token("idRefresh", 3000),
+				"antiCsrfToken":  "csrf",
+			})
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+}
+
+func Test_NewSession_Options(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := newSessionTestCore(t, &requestBody)
+	defer server.Close()
+	client, err := New(ConfigMap{Hosts: server.URL})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	result, err := client.NewSession(w, "user",
+		WithJWTPayload(testClaims{Role: "admin"}),
+		WithSessionData(map[string]interface{}{"theme": "dark"}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", requestBody["userDataInJWT"].(map[string]interface{})["role"])
+	assert.Equal(t, "dark", requestBody["userDataInDatabase"].(map[string]interface{})["theme"])
+	assert.Equal(t, "handle", result.Session.GetHandle())
+	assert.Equal(t, "csrf", result.AntiCsrfToken)
+	assert.Equal(t, time.Unix(1, 0), result.AccessTokenExpiry)
+	assert.Equal(t, time.Unix(2, 0), result.RefreshTokenExpiry)
+	assert.Equal(t, time.Unix(3, 0), result.IDRefreshTokenExpiry)
+
+	assert.Len(t, w.Header()["Set-Cookie"], 3)
+}
+
+func Test_NewSession_TokenTransfer(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := newSessionTestCore(t, &requestBody)
+	defer server.Close()
+	client, err := New(ConfigMap{Hosts: server.URL})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	_, err = client.NewSession(w, "user", WithTokenTransfer(TokenTransferMethodHeader))
+	assert.NoError(t, err)
+	for _, cookie := range w.Header()["Set-Cookie"] {
+		assert.False(t, strings.HasPrefix(cookie, accessTokenCookieKey+"="))
+		assert.False(t, strings.HasPrefix(cookie, refreshTokenCookieKey+"="))
+	}
+	assert.Equal(t, "access", w.Header().Get(accessTokenHeaderKey))
+	assert.Equal(t, "refresh", w.Header().Get(refreshTokenHeaderKey))
+
+	_, err = client.NewSession(httptest.NewRecorder(), "user", WithTokenTransfer("query"))
+	assert.Equal(t, errors.ReasonInvalidConfig, errors.GetReason(err))
+}
+
+func Test_NewSession_UnsupportedOptions(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := newSessionTestCore(t, &requestBody)
+	defer server.Close()
+	client, err := New(ConfigMap{Hosts: server.URL})
+	assert.NoError(t, err)
+
+	_, err = client.NewSession(httptest.NewRecorder(), "user", WithAccessTokenLifetime(time.Minute))
+	assert.Equal(t, errors.ReasonUnsupportedOption, errors.GetReason(err))
+	assert.Nil(t, requestBody)
+
+	// the option is rejected without querying the core
+	unreachable, err := New(ConfigMap{Hosts: "http://localhost:1"})
+	assert.NoError(t, err)
+	_, err = unreachable.NewSession(httptest.NewRecorder(), "user", WithAccessTokenLifetime(time.Minute))
+	assert.Equal(t, errors.ReasonUnsupportedOption, errors.GetReason(err))
+
+	_, err = client.NewSession(httptest.NewRecorder(), "user", WithJWTPayload([]string{"not", "an", "object"}))
+	assert.Equal(t, errors.ReasonInvalidPayload, errors.GetReason(err))
+
+	_, err = client.CreateNewSession(httptest.NewRecorder(), "user", nil, nil, nil)
+	assert.Equal(t, errors.ReasonInvalidPayload, errors.GetReason(err), "extra maps should not be ignored")
+	assert.Nil(t, requestBody)
+}
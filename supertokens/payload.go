@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// SessionOptions for creating a session with typed values. JWTPayload and SessionData can be
+// structs, maps or anything else that encodes to a JSON object
+type SessionOptions struct {
+	JWTPayload  interface{}
+	SessionData interface{}
+}
+
+// encodePayload converts a value to the map sent to the core by round tripping it through JSON
+func encodePayload(name string, value interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	if value == nil {
+		return result, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.GeneralError{
+			Msg:         "Could not encode the " + name + ": " + err.Error(),
+			ActualError: err,
+			Reason:      errors.ReasonInvalidPayload,
+		}
+	}
+	if string(encoded) == "null" {
+		return result, nil
+	}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, errors.GeneralError{
+			Msg:         "The " + name + " must encode to a JSON object: " + err.Error(),
+			ActualError: err,
+			Reason:      errors.ReasonInvalidPayload,
+		}
+	}
+	return result, nil
+}
+
+// decodePayload fills target, which must be a pointer, from a map received from the core. Keys that are not
+// fields of a struct target are an error. Fields missing from the payload are left at their zero value, since
+// JSON decoding cannot tell them apart, so use pointer fields for the ones that must be checked
+func decodePayload(name string, payload map[string]interface{}, target interface{}) error {
+	encoded, err := json.Marshal(payload)
+	if err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(target)
+	}
+	if err != nil {
+		return errors.GeneralError{
+			Msg:         "The " + name + " does not match the given type: " + err.Error(),
+			ActualError: err,
+			Reason:      errors.ReasonInvalidPayload,
+		}
+	}
+	return nil
+}
+
+// CreateNewSessionWithOptions function used to create a new SuperTokens session with a typed JWT payload and session data
+func CreateNewSessionWithOptions(response http.ResponseWriter, userID string, options SessionOptions) (Session, error) {
+	return defaultClient.CreateNewSessionWithOptions(response, userID, options)
+}
+
+// CreateNewSessionWithOptionsAndContext function used to create a new SuperTokens session with a typed JWT payload and session data,
+// aborting the query to the core when ctx is done
+func CreateNewSessionWithOptionsAndContext(ctx context.Context, response http.ResponseWriter, userID string, options SessionOptions) (Session, error) {
+	return defaultClient.CreateNewSessionWithOptionsAndContext(ctx, response, userID, options)
+}
+
+// CreateNewSessionWithOptions function used to create a new SuperTokens session with a typed JWT payload and session data
+func (client *Client) CreateNewSessionWithOptions(response http.ResponseWriter, userID string, options SessionOptions) (Session, error) {
+	return client.CreateNewSessionWithOptionsAndContext(context.Background(), response, userID, options)
+}
+
+// CreateNewSessionWithOptionsAndContext function used to create a new SuperTokens session with a typed JWT payload and session data,
+// aborting the query to the core when ctx is done
+func (client *Client) CreateNewSessionWithOptionsAndContext(ctx context.Context, response http.ResponseWriter, userID string, options SessionOptions) (Session, error) {
+	jwtPayload, err := encodePayload("JWT payload", options.JWTPayload)
+	if err != nil {
+		return Session{}, err
+	}
+	sessionData, err := encodePayload("session data", options.SessionData)
+	if err != nil {
+		return Session{}, err
+	}
+	return client.CreateNewSessionWithContext(ctx, response, userID, jwtPayload, sessionData)
+}
+
+// DecodeJWTPayload fills target, which must be a pointer, with the jwt payload for this session
+func (session *Session) DecodeJWTPayload(target interface{}) error {
+	return decodePayload("JWT payload", session.userDataInJWT, target)
+}
+
+// DecodeSessionData fills target, which must be a pointer, with the session data for this session
+func (session *Session) DecodeSessionData(target interface{}) error {
+	return session.DecodeSessionDataWithContext(context.Background(), target)
+}
+
+// DecodeSessionDataWithContext fills target, which must be a pointer, with the session data for this session,
+// aborting the query to the core when ctx is done
+func (session *Session) DecodeSessionDataWithContext(ctx context.Context, target interface{}) error {
+	data, err := session.GetSessionDataWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	return decodePayload("session data", data, target)
+}
+
+// UpdateJWTPayloadFrom function used to update jwt payload for this session with a typed value
+func (session *Session) UpdateJWTPayloadFrom(newJWTPayload interface{}) error {
+	return session.UpdateJWTPayloadFromWithContext(context.Background(), newJWTPayload)
+}
+
+// UpdateJWTPayloadFromWithContext function used to update jwt payload for this session with a typed value,
+// aborting the query to the core when ctx is done
+func (session *Session) UpdateJWTPayloadFromWithContext(ctx context.Context, newJWTPayload interface{}) error {
+	payload, err := encodePayload("JWT payload", newJWTPayload)
+	if err != nil {
+		return err
+	}
+	return session.UpdateJWTPayloadWithContext(ctx, payload)
+}
+
+// UpdateSessionDataFrom function used to update session data for this session with a typed value
+func (session *Session) UpdateSessionDataFrom(newSessionData interface{}) error {
+	return session.UpdateSessionDataFromWithContext(context.Background(), newSessionData)
+}
+
+// UpdateSessionDataFromWithContext function used to update session data for this session with a typed value,
+// aborting the query to the core when ctx is done
+func (session *Session) UpdateSessionDataFromWithContext(ctx context.Context, newSessionData interface{}) error {
+	data, err := encodePayload("session data", newSessionData)
+	if err != nil {
+		return err
+	}
+	return session.UpdateSessionDataWithContext(ctx, data)
+}
@@ -0,0 +1,55 @@
+package supertokens
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+type testClaims struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	Level       int      `json:"level"`
+}
+
+func Test_encodePayload(t *testing.T) {
+	payload, err := encodePayload("JWT payload", testClaims{Role: "admin", Permissions: []string{"read"}, Level: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", payload["role"])
+	assert.Equal(t, float64(2), payload["level"])
+
+	payload, err = encodePayload("JWT payload", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, payload)
+
+	_, err = encodePayload("JWT payload", []string{"not", "an", "object"})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ReasonInvalidPayload, errors.GetReason(err))
+}
+
+func Test_Session_DecodeJWTPayload(t *testing.T) {
+	session := Session{userDataInJWT: map[string]interface{}{
+		"role":        "admin",
+		"permissions": []interface{}{"read", "write"},
+		"level":       float64(2),
+	}}
+	var claims testClaims
+	assert.NoError(t, session.DecodeJWTPayload(&claims))
+	assert.Equal(t, testClaims{Role: "admin", Permissions: []string{"read", "write"}, Level: 2}, claims)
+
+	session.userDataInJWT["level"] = "high"
+	err := session.DecodeJWTPayload(&claims)
+	assert.Error(t, err)
+	assert.Equal(t, errors.ReasonInvalidPayload, errors.GetReason(err))
+
+	session.userDataInJWT["level"] = float64(2)
+	session.userDataInJWT["team"] = "billing"
+	err = session.DecodeJWTPayload(&claims)
+	assert.Error(t, err, "keys that are not fields of the struct should be rejected")
+	assert.Equal(t, errors.ReasonInvalidPayload, errors.GetReason(err))
+
+	var payload map[string]interface{}
+	assert.NoError(t, session.DecodeJWTPayload(&payload))
+	assert.Equal(t, "billing", payload["team"])
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"net/http"
+)
+
+const defaultSignOutAPIPath = "/signout"
+
+// Routes returns a handler that refreshes the session on a POST to the refresh API path, and revokes it on a POST
+// to the SignOutAPIPath. Other requests get a 404, so it can be mounted on any router, for example as its not found handler
+func Routes() http.Handler {
+	return defaultClient.Routes()
+}
+
+// Routes returns a handler that refreshes the session on a POST to the refresh API path of this client, and revokes
+// it on a POST to its SignOutAPIPath. Other requests get a 404, so it can be mounted on any router
+func (client *Client) Routes() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+		if isAPIPath(r.URL.Path, client.getSignOutAPIPath()) {
+			client.signOut(w, r)
+			return
+		}
+		refreshAPIPath, err := client.getRefreshAPIPath(r.Context())
+		if err != nil {
+			client.HandleErrorAndRespond(err, w)
+			return
+		}
+		if isAPIPath(r.URL.Path, refreshAPIPath) {
+			// the middleware refreshes the session itself if the route is behind it
+			if GetSessionFromRequest(r) == nil {
+				if _, err := client.RefreshSession(w, r); err != nil {
+					client.HandleErrorAndRespond(err, w)
+					return
+				}
+			}
+			w.Write([]byte(""))
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+// signOut revokes the session of the request, if there is one, and clears its cookies
+func (client *Client) signOut(w http.ResponseWriter, r *http.Request) {
+	session := GetSessionFromRequest(r)
+	if session == nil {
+		var err error
+		session, err = client.GetOptionalSession(w, r, true)
+		if err != nil {
+			client.HandleErrorAndRespond(err, w)
+			return
+		}
+	}
+	if session != nil {
+		if err := session.RevokeSessionWithContext(r.Context()); err != nil {
+			client.HandleErrorAndRespond(err, w)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"OK"}`))
+}
+
+// MiddlewareFunc returns Middleware in the form routers use, such as chi's Router.Use and gorilla's mux.MiddlewareFunc.
+// Handlers read the session with GetSessionFromRequest
+func MiddlewareFunc(options MiddlewareOptions) func(http.Handler) http.Handler {
+	return defaultClient.MiddlewareFunc(options)
+}
+
+// MiddlewareFunc returns the Middleware of this client in the form routers use, such as chi's Router.Use and
+// gorilla's mux.MiddlewareFunc. Handlers read the session with GetSessionFromRequest
+func (client *Client) MiddlewareFunc(options MiddlewareOptions) func(http.Handler) http.Handler {
+	return func(theirHandler http.Handler) http.Handler {
+		return client.MiddlewareWithOptions(theirHandler, options)
+	}
+}
+
+// getRefreshAPIPath returns the RefreshAPIPath of the config, or else the refresh token path of the core
+func (client *Client) getRefreshAPIPath(ctx context.Context) (string, error) {
+	refreshAPIPath := client.config.RefreshAPIPath
+	// with a pinned signing key the core may not be reachable, so the handshake is not needed
+	if refreshAPIPath == "" && !client.instance.IsOfflineVerificationEnabled() {
+		handshakeInfo, err := client.instance.GetHandshakeInfo(ctx)
+		if err != nil {
+			return "", err
+		}
+		refreshAPIPath = handshakeInfo.RefreshTokenPath
+	}
+	return refreshAPIPath, nil
+}
+
+func (client *Client) getSignOutAPIPath() string {
+	if client.config.SignOutAPIPath == "" {
+		return defaultSignOutAPIPath
+	}
+	return client.config.SignOutAPIPath
+}
+
+// isAPIPath tells if the request path is the API path, ignoring a trailing slash. An empty API path matches nothing
+func isAPIPath(path string, apiPath string) bool {
+	if apiPath == "" {
+		return false
+	}
+	return apiPath == path || (apiPath+"/") == path || apiPath == (path+"/")
+}
@@ -0,0 +1,91 @@
+package supertokens
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/supertokenstest"
+)
+
+// muxMiddlewareFunc has the type of gorilla's mux.MiddlewareFunc
+type muxMiddlewareFunc func(http.Handler) http.Handler
+
+func Test_Routes(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+	routes := client.Routes()
+
+	recorder := httptest.NewRecorder()
+	routes.ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/refresh/"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 1, core.RequestCount("/session/refresh"))
+	assert.NotEmpty(t, recorder.Result().Cookies())
+
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest("POST", "/refresh", nil))
+	assert.Equal(t, 401, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/signout"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, `{"status":"OK"}`, recorder.Body.String())
+	handles, err := client.GetAllSessionHandlesForUser("user")
+	assert.NoError(t, err)
+	assert.Len(t, handles, 1, "only the session that was refreshed is left")
+	for _, cookie := range recorder.Result().Cookies() {
+		assert.Empty(t, cookie.Value, "the cookies are cleared")
+	}
+
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest("POST", "/signout", nil))
+	assert.Equal(t, 200, recorder.Code, "signing out without a session succeeds")
+
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest("GET", "/refresh", nil))
+	assert.Equal(t, 404, recorder.Code)
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest("POST", "/other", nil))
+	assert.Equal(t, 404, recorder.Code)
+}
+
+func Test_Routes_SignOutAPIPath(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL(), RefreshAPIPath: "/auth/refresh", SignOutAPIPath: "/auth/signout"})
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	client.Routes().ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/auth/signout"))
+	assert.Equal(t, 200, recorder.Code)
+	recorder = httptest.NewRecorder()
+	client.Routes().ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/auth/refresh"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 1, core.RequestCount("/session/refresh"))
+	recorder = httptest.NewRecorder()
+	client.Routes().ServeHTTP(recorder, httptest.NewRequest("POST", "/signout", nil))
+	assert.Equal(t, 404, recorder.Code)
+}
+
+func Test_MiddlewareFunc(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := New(ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	var middleware muxMiddlewareFunc = client.MiddlewareFunc(MiddlewareOptions{})
+	handler := middleware(sessionUserHandler{})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "GET", "/user"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user", recorder.Body.String())
+
+	// routes behind the middleware do not refresh the session a second time
+	recorder = httptest.NewRecorder()
+	middleware(client.Routes()).ServeHTTP(recorder, newMiddlewareTestRequest(t, client, "POST", "/refresh"))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 1, core.RequestCount("/session/refresh"))
+}
@@ -17,7 +17,9 @@
 package supertokens
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/supertokens/supertokens-go/supertokens/core"
 	"github.com/supertokens/supertokens-go/supertokens/errors"
@@ -30,20 +32,33 @@ type Session struct {
 	userDataInJWT map[string]interface{}
 	accessToken   string
 	response      http.ResponseWriter
+	// request that this session was read from or created for. It is nil if it is not known
+	request *http.Request
+	client  *Client
+	// tokenTransferMethod is how new tokens of this session are sent: cookie, header, or any for new sessions
+	tokenTransferMethod string
 }
 
 // RevokeSession function used to revoke a session for this session
 func (session *Session) RevokeSession() error {
-	success, err := RevokeSession(session.sessionHandle)
+	return session.RevokeSessionWithContext(context.Background())
+}
+
+// RevokeSessionWithContext function used to revoke a session for this session, aborting the query to the core when ctx is done
+func (session *Session) RevokeSessionWithContext(ctx context.Context) error {
+	success, err := session.client.instance.RevokeSession(ctx, session.sessionHandle)
 	if err != nil {
 		return err
 	}
 	if success {
-		handShakeInfo, handShakeInfoErr := core.GetHandshakeInfoInstance()
+		session.client.emitSessionEvent(SessionRevoked, session.userID, session.sessionHandle, session.request)
+	}
+	if success && session.tokenTransferMethod != TokenTransferMethodHeader {
+		handShakeInfo, handShakeInfoErr := session.client.instance.GetHandshakeInfo(ctx)
 		if handShakeInfoErr != nil {
 			return handShakeInfoErr
 		}
-		clearSessionFromCookie(session.response,
+		session.client.clearSessionFromCookie(session.response,
 			handShakeInfo.CookieDomain,
 			handShakeInfo.CookieSecure,
 			handShakeInfo.AccessTokenPath,
@@ -57,14 +72,19 @@ func (session *Session) RevokeSession() error {
 
 // GetSessionData function used to get session data for this session
 func (session *Session) GetSessionData() (map[string]interface{}, error) {
-	data, err := GetSessionData(session.sessionHandle)
+	return session.GetSessionDataWithContext(context.Background())
+}
+
+// GetSessionDataWithContext function used to get session data for this session, aborting the query to the core when ctx is done
+func (session *Session) GetSessionDataWithContext(ctx context.Context) (map[string]interface{}, error) {
+	data, err := session.client.GetSessionDataWithContext(ctx, session.sessionHandle)
 	if err != nil {
-		if errors.IsUnauthorizedError(err) {
-			handShakeInfo, handShakeInfoErr := core.GetHandshakeInfoInstance()
+		if errors.IsUnauthorizedError(err) && session.tokenTransferMethod != TokenTransferMethodHeader {
+			handShakeInfo, handShakeInfoErr := session.client.instance.GetHandshakeInfo(ctx)
 			if handShakeInfoErr != nil {
 				return nil, handShakeInfoErr
 			}
-			clearSessionFromCookie(session.response,
+			session.client.clearSessionFromCookie(session.response,
 				handShakeInfo.CookieDomain,
 				handShakeInfo.CookieSecure,
 				handShakeInfo.AccessTokenPath,
@@ -80,14 +100,19 @@ func (session *Session) GetSessionData() (map[string]interface{}, error) {
 
 // UpdateSessionData function used to update session data for this session
 func (session *Session) UpdateSessionData(newSessionData map[string]interface{}) error {
-	err := UpdateSessionData(session.sessionHandle, newSessionData)
+	return session.UpdateSessionDataWithContext(context.Background(), newSessionData)
+}
+
+// UpdateSessionDataWithContext function used to update session data for this session, aborting the query to the core when ctx is done
+func (session *Session) UpdateSessionDataWithContext(ctx context.Context, newSessionData map[string]interface{}) error {
+	err := session.client.UpdateSessionDataWithContext(ctx, session.sessionHandle, newSessionData)
 	if err != nil {
-		if errors.IsUnauthorizedError(err) {
-			handShakeInfo, handShakeInfoErr := core.GetHandshakeInfoInstance()
+		if errors.IsUnauthorizedError(err) && session.tokenTransferMethod != TokenTransferMethodHeader {
+			handShakeInfo, handShakeInfoErr := session.client.instance.GetHandshakeInfo(ctx)
 			if handShakeInfoErr != nil {
 				return handShakeInfoErr
 			}
-			clearSessionFromCookie(session.response,
+			session.client.clearSessionFromCookie(session.response,
 				handShakeInfo.CookieDomain,
 				handShakeInfo.CookieSecure,
 				handShakeInfo.AccessTokenPath,
@@ -121,16 +146,66 @@ func (session *Session) GetAccessToken() string {
 	return session.accessToken
 }
 
+// GetAccessTokenExpiry function gets the time at which the current access token of this session expires.
+// It returns an error if the access token cannot be read
+func (session *Session) GetAccessTokenExpiry() (time.Time, error) {
+	metadata, err := core.GetAccessTokenMetadata(session.accessToken)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return getTimeFromMS(metadata.Expiry), nil
+}
+
+// GetAccessTokenTimeCreated function gets the time at which the current access token of this session was created.
+// Every refresh issues a new access token, so this is not when the session was created. The supported cores do
+// not return the creation time of a session. It returns an error if the access token cannot be read
+func (session *Session) GetAccessTokenTimeCreated() (time.Time, error) {
+	metadata, err := core.GetAccessTokenMetadata(session.accessToken)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return getTimeFromMS(metadata.TimeCreated), nil
+}
+
+// GetAntiCsrfToken function gets the anti-csrf token of this session. It is empty if anti-csrf is disabled in the core.
+// It returns an error if the access token cannot be read
+func (session *Session) GetAntiCsrfToken() (string, error) {
+	metadata, err := core.GetAccessTokenMetadata(session.accessToken)
+	if err != nil {
+		return "", err
+	}
+	if metadata.AntiCsrfToken == nil {
+		return "", nil
+	}
+	return *metadata.AntiCsrfToken, nil
+}
+
+// IsFreshlyRefreshed function tells if the access token of this session was issued by a refresh
+// that has not yet been confirmed by the core. This is true for sessions returned by RefreshSession.
+// It returns an error if the access token cannot be read
+func (session *Session) IsFreshlyRefreshed() (bool, error) {
+	metadata, err := core.GetAccessTokenMetadata(session.accessToken)
+	if err != nil {
+		return false, err
+	}
+	return metadata.ParentRefreshTokenHash1 != nil, nil
+}
+
 // UpdateJWTPayload function used to update jwt payload for this session
 func (session *Session) UpdateJWTPayload(newJWTPayload map[string]interface{}) error {
-	sessionInfo, err := core.RegenerateSession(session.accessToken, newJWTPayload)
+	return session.UpdateJWTPayloadWithContext(context.Background(), newJWTPayload)
+}
+
+// UpdateJWTPayloadWithContext function used to update jwt payload for this session, aborting the query to the core when ctx is done
+func (session *Session) UpdateJWTPayloadWithContext(ctx context.Context, newJWTPayload map[string]interface{}) error {
+	sessionInfo, err := session.client.instance.RegenerateSession(ctx, session.accessToken, newJWTPayload)
 	if err != nil {
-		if errors.IsUnauthorizedError(err) {
-			handShakeInfo, handShakeInfoErr := core.GetHandshakeInfoInstance()
+		if errors.IsUnauthorizedError(err) && session.tokenTransferMethod != TokenTransferMethodHeader {
+			handShakeInfo, handShakeInfoErr := session.client.instance.GetHandshakeInfo(ctx)
 			if handShakeInfoErr != nil {
 				return handShakeInfoErr
 			}
-			clearSessionFromCookie(session.response,
+			session.client.clearSessionFromCookie(session.response,
 				handShakeInfo.CookieDomain,
 				handShakeInfo.CookieSecure,
 				handShakeInfo.AccessTokenPath,
@@ -152,15 +227,8 @@ func (session *Session) UpdateJWTPayload(newJWTPayload map[string]interface{}) e
 			session.userDataInJWT,
 		)
 
-		attachAccessTokenToCookie(
-			session.response,
-			(*sessionInfo.AccessToken).Token,
-			(*sessionInfo.AccessToken).Expiry,
-			(*sessionInfo.AccessToken).Domain,
-			(*sessionInfo.AccessToken).CookieSecure,
-			(*sessionInfo.AccessToken).CookiePath,
-			(*sessionInfo.AccessToken).SameSite,
-		)
+		session.client.attachAccessToken(session.response, *sessionInfo.AccessToken, session.tokenTransferMethod)
 	}
+	session.client.emitSessionEvent(JWTPayloadUpdated, session.userID, session.sessionHandle, session.request)
 	return nil
 }
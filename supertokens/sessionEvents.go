@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+package supertokens
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// SessionEvent describes a change to a session. UserID and SessionHandle are empty when they are not known
+type SessionEvent = core.SessionEvent
+
+// SessionEventType is the kind of change that happened to a session
+type SessionEventType = core.SessionEventType
+
+const (
+	// SessionCreated is emitted by CreateNewSession and NewSession. Pass WithRequest to NewSession to set the event's request
+	SessionCreated = core.SessionEventCreated
+	// SessionRefreshed is emitted by RefreshSession
+	SessionRefreshed = core.SessionEventRefreshed
+	// JWTPayloadUpdated is emitted by Session.UpdateJWTPayload
+	JWTPayloadUpdated = core.SessionEventJWTPayloadUpdated
+	// SessionRevoked is emitted for every session revoked through this package
+	SessionRevoked = core.SessionEventRevoked
+	// TokenTheftDetected is emitted by RefreshSession when a refresh token is reused
+	TokenTheftDetected = core.SessionEventTokenTheftDetected
+	// Unauthorized is emitted by GetSession and RefreshSession when the session does not exist or its tokens are missing
+	Unauthorized = core.SessionEventUnauthorized
+)
+
+// OnSessionEvent function to be notified of session events of the given types, or of all of them if no type is given
+func OnSessionEvent(handler func(SessionEvent), types ...SessionEventType) {
+	defaultClient.OnSessionEvent(handler, types...)
+}
+
+// OnSessionEvent function to be notified of session events of this client of the given types, or of all of them
+// if no type is given. Handlers are called synchronously, so they should not block
+func (client *Client) OnSessionEvent(handler func(SessionEvent), types ...SessionEventType) {
+	client.instance.OnSessionEvent(handler, types...)
+}
+
+func (client *Client) emitSessionEvent(eventType SessionEventType, userID string, sessionHandle string, request *http.Request) {
+	client.instance.EmitSessionEvent(SessionEvent{
+		Type:          eventType,
+		UserID:        userID,
+		SessionHandle: sessionHandle,
+		Request:       request,
+	})
+}
+
+func (client *Client) emitSessionsRevoked(userID string, sessionHandles []string, request *http.Request) {
+	for _, sessionHandle := range sessionHandles {
+		client.emitSessionEvent(SessionRevoked, userID, sessionHandle, request)
+	}
+}
+
+// emitSessionErrorEvent emits the event, if any, for an error of GetSession or RefreshSession
+func (client *Client) emitSessionErrorEvent(err error, request *http.Request) {
+	var tokenTheftDetectedError errors.TokenTheftDetectedError
+	if goerrors.As(err, &tokenTheftDetectedError) {
+		client.emitSessionEvent(TokenTheftDetected, tokenTheftDetectedError.UserID, tokenTheftDetectedError.SessionHandle, request)
+	} else if errors.IsUnauthorizedError(err) {
+		client.emitSessionEvent(Unauthorized, "", "", request)
+	}
+}
@@ -0,0 +1,69 @@
+package supertokens
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SessionEvents(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := newSessionTestCore(t, &requestBody)
+	defer server.Close()
+	client, err := New(ConfigMap{Hosts: server.URL})
+	assert.NoError(t, err)
+
+	var events []SessionEvent
+	client.OnSessionEvent(func(event SessionEvent) {
+		events = append(events, event)
+	})
+	var unauthorized int
+	client.OnSessionEvent(func(event SessionEvent) {
+		unauthorized++
+	}, Unauthorized)
+
+	request := httptest.NewRequest("POST", "/login", nil)
+	_, err = client.NewSession(httptest.NewRecorder(), "user", WithRequest(request))
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, SessionCreated, events[0].Type)
+	assert.Equal(t, "user", events[0].UserID)
+	assert.Equal(t, "handle", events[0].SessionHandle)
+	assert.Equal(t, request, events[0].Request)
+	assert.False(t, events[0].Time.IsZero())
+
+	request = httptest.NewRequest("GET", "/", nil)
+	_, err = client.GetSession(httptest.NewRecorder(), request, false)
+	assert.Error(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, Unauthorized, events[1].Type)
+	assert.Equal(t, request, events[1].Request)
+	assert.Equal(t, 1, unauthorized)
+}
+
+func Test_SessionEvents_Revoked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiversion":
+			w.Write([]byte(`{"versions":["2.3"]}`))
+		case "/session/remove":
+			w.Write([]byte(`{"status":"OK","sessionHandlesRevoked":["a","b"]}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	client, err := New(ConfigMap{Hosts: server.URL})
+	assert.NoError(t, err)
+
+	var revoked []string
+	client.OnSessionEvent(func(event SessionEvent) {
+		revoked = append(revoked, event.SessionHandle)
+	}, SessionRevoked)
+
+	_, err = client.RevokeMultipleSessions([]string{"a", "b", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, revoked)
+}
@@ -0,0 +1,97 @@
+package supertokens
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func Test_Session_Metadata(t *testing.T) {
+	_, accessToken := createSignedAccessToken(t, "csrf")
+	session := Session{accessToken: accessToken}
+	expiry, err := session.GetAccessTokenExpiry()
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiry, time.Minute)
+	timeCreated, err := session.GetAccessTokenTimeCreated()
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), timeCreated, time.Minute)
+	antiCsrfToken, err := session.GetAntiCsrfToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "csrf", antiCsrfToken)
+	freshlyRefreshed, err := session.IsFreshlyRefreshed()
+	assert.NoError(t, err)
+	assert.False(t, freshlyRefreshed)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"sessionHandle":           "handle",
+		"userId":                  "userId",
+		"refreshTokenHash1":       "hash",
+		"parentRefreshTokenHash1": "parentHash",
+		"userData":                map[string]interface{}{},
+		"expiryTime":              2000,
+		"timeCreated":             1000,
+	})
+	assert.NoError(t, err)
+	session = Session{accessToken: "header." + base64.StdEncoding.EncodeToString(payload) + ".signature"}
+	expiry, err = session.GetAccessTokenExpiry()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(2, 0), expiry)
+	timeCreated, err = session.GetAccessTokenTimeCreated()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1, 0), timeCreated)
+	antiCsrfToken, err = session.GetAntiCsrfToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "", antiCsrfToken)
+	freshlyRefreshed, err = session.IsFreshlyRefreshed()
+	assert.NoError(t, err)
+	assert.True(t, freshlyRefreshed)
+
+	session = Session{accessToken: "not a token"}
+	_, err = session.GetAccessTokenExpiry()
+	assert.Error(t, err)
+	_, err = session.GetAccessTokenTimeCreated()
+	assert.Error(t, err)
+	_, err = session.GetAntiCsrfToken()
+	assert.Error(t, err)
+	_, err = session.IsFreshlyRefreshed()
+	assert.Error(t, err)
+}
+
+func Test_GetSessionInformation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiversion":
+			w.Write([]byte(`{"versions":["2.3"]}`))
+		case "/session/data":
+			if r.URL.Query().Get("sessionHandle") != "handle" {
+				w.Write([]byte(`{"status":"UNAUTHORISED","message":"Session does not exist."}`))
+				return
+			}
+			w.Write([]byte(`{"status":"OK","userDataInDatabase":{"theme":"dark"}}`))
+		case "/jwt/data":
+			w.Write([]byte(`{"status":"OK","userDataInJWT":{"role":"admin"}}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	client, err := New(ConfigMap{Hosts: server.URL})
+	assert.NoError(t, err)
+
+	information, err := client.GetSessionInformation("handle")
+	assert.NoError(t, err)
+	assert.Equal(t, SessionInformation{
+		SessionHandle: "handle",
+		SessionData:   map[string]interface{}{"theme": "dark"},
+		JWTPayload:    map[string]interface{}{"role": "admin"},
+	}, information)
+
+	_, err = client.GetSessionInformation("unknown")
+	assert.True(t, errors.IsUnauthorizedError(err))
+}
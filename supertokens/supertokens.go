@@ -17,10 +17,12 @@
 package supertokens
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"github.com/supertokens/supertokens-go/supertokens/claims"
 	"github.com/supertokens/supertokens-go/supertokens/core"
-	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
 type contextKey int
@@ -36,320 +38,290 @@ type ConfigMap struct {
 	CookieSecure    *bool
 	CookieSameSite  string
 	APIKey          string
+	// HTTPClient used to query the cores. Set its Transport to control connection pooling
+	HTTPClient *http.Client
+	// RequestTimeout for a single attempt of a query to a core. Zero means no timeout
+	RequestTimeout time.Duration
+	// RetryPolicy for queries that time out, fail with a 5xx response or cannot reach a core
+	RetryPolicy *RetryPolicy
+	// HealthCheck configures when cores are taken out of rotation and probed until they recover
+	HealthCheck *HealthCheckOptions
+	// SigningKey configures background refreshing of the JWT signing key and how long rotated out keys are accepted
+	SigningKey *SigningKeyOptions
+	// JWTSigningPublicKey pins the key used to verify access tokens. GetSession then never queries the core
+	JWTSigningPublicKey string
+	// JWTSigningPublicKeyPath is a file with the key used to verify access tokens, read again when it changes.
+	// GetSession then never queries the core
+	JWTSigningPublicKeyPath string
+	// TokenTransferMethod is one of cookie (the default), header or any. See TokenTransferMethodCookie
+	TokenTransferMethod string
+	// Logger receives debug events about session verification, queries to the cores and cleared cookies.
+	// A *slog.Logger can be used. By default nothing is logged, unless the DEBUG environment variable
+	// contains com.supertokens
+	Logger Logger
+	// Metrics receives counters of session events and verifications, and the duration of queries to the cores.
+	// See the names starting with Metric. Nothing is recorded by default
+	Metrics Metrics
+	// Tracer traces GetSession and every query to the cores, and sends the trace context to the cores.
+	// Nothing is traced by default
+	Tracer Tracer
+	// SignOutAPIPath is where Routes revokes the session of a POST request. Defaults to /signout
+	SignOutAPIPath string
+	// ClaimValidators check the JWT payload of every session verified by Middleware. Use RequireClaims
+	// for claims that only some handlers need
+	ClaimValidators []claims.Validator
 }
 
-// Config used to set locations of SuperTokens instances
-func Config(config ConfigMap) {
-	configCookieAndHeaders(config)
-	core.Config(config.Hosts, config.APIKey)
-}
+// RetryPolicy decides how often and how quickly failed queries to the core are retried.
+// Queries to a core that refuses the connection or cannot be resolved are always sent to the next core.
+// Queries that time out or get a 5xx response are only retried with a RetryPolicy
+type RetryPolicy = core.RetryPolicy
 
-// CreateNewSession function used to create a new SuperTokens session
-func CreateNewSession(response http.ResponseWriter,
-	userID string, payload ...map[string]interface{}) (Session, error) {
+// HealthCheckOptions configures when a core is taken out of rotation and how it is probed to bring it back
+type HealthCheckOptions = core.HealthCheckOptions
 
-	var jwtPayload = map[string]interface{}{}
-	var sessionData = map[string]interface{}{}
-	if len(payload) == 1 && payload[0] != nil {
-		jwtPayload = payload[0]
-	} else if len(payload) == 2 {
-		if payload[0] != nil {
-			jwtPayload = payload[0]
-		}
-		if payload[1] != nil {
-			sessionData = payload[1]
-		}
-	}
+// CoreHostStatus is the health of one of the configured cores
+type CoreHostStatus = core.HostStatus
 
-	session, err := core.CreateNewSession(userID, jwtPayload, sessionData)
+// Logger receives structured log events. See core.Logger
+type Logger = core.Logger
 
-	if err != nil {
-		return Session{}, err
-	}
+// Metrics receives the measurements of the SDK. See core.Metrics
+type Metrics = core.Metrics
 
-	//attach token to cookies
-	accessToken := session.AccessToken
-	refreshToken := session.RefreshToken
-	idRefreshToken := session.IDRefreshToken
-
-	attachFrontTokenInHeaders(
-		response,
-		session.UserID,
-		accessToken.Expiry,
-		session.UserDataInJWT,
-	)
-
-	attachAccessTokenToCookie(
-		response,
-		accessToken.Token,
-		accessToken.Expiry,
-		accessToken.Domain,
-		accessToken.CookieSecure,
-		accessToken.CookiePath,
-		accessToken.SameSite,
-	)
-
-	attachRefreshTokenToCookie(
-		response,
-		refreshToken.Token,
-		refreshToken.Expiry,
-		refreshToken.Domain,
-		refreshToken.CookieSecure,
-		refreshToken.CookiePath,
-		refreshToken.SameSite,
-	)
-
-	setIDRefreshTokenInHeaderAndCookie(
-		response,
-		idRefreshToken.Token,
-		idRefreshToken.Expiry,
-		idRefreshToken.Domain,
-		idRefreshToken.CookieSecure,
-		idRefreshToken.CookiePath,
-		idRefreshToken.SameSite,
-	)
-
-	if session.AntiCsrfToken != nil {
-		setAntiCsrfTokenInHeaders(response, *session.AntiCsrfToken)
-	}
+// Names of the metrics recorded by the SDK. See core.MetricSessionEvents
+const (
+	MetricSessionEvents        = core.MetricSessionEvents
+	MetricSessionVerifications = core.MetricSessionVerifications
+	MetricCoreFailovers        = core.MetricCoreFailovers
+	MetricCoreRequestDuration  = core.MetricCoreRequestDuration
+)
 
-	return Session{
-		accessToken:   accessToken.Token,
-		sessionHandle: session.Handle,
-		userID:        session.UserID,
-		userDataInJWT: session.UserDataInJWT,
-		response:      response,
-	}, nil
+// Tracer starts spans around the work of the SDK. See core.Tracer
+type Tracer = core.Tracer
 
-}
+// SigningKeyOptions configures how the JWT signing public key is kept up to date
+type SigningKeyOptions = core.SigningKeyOptions
 
-// GetSession function used to verify a session
-func GetSession(response http.ResponseWriter, request *http.Request,
-	doAntiCsrfCheck bool) (Session, error) {
-	saveFrontendInfoFromRequest(request)
+// SigningKeyRotation describes a change of the JWT signing public key
+type SigningKeyRotation = core.SigningKeyRotation
 
-	idRefreshToken := getIDRefreshTokenFromCookie(request)
-	if idRefreshToken == nil {
-		return Session{}, errors.UnauthorizedError{
-			Msg: "idRefreshToken missing",
-		}
-	}
+// SessionInformation is what the core stores for a session. The supported cores (CDI 2.0 to 2.3) do not
+// return the user, creation time or expiry of a session by its handle, so they are not included. For a
+// verified Session, GetUserID and GetAccessTokenExpiry return the user and the expiry of its access token
+type SessionInformation struct {
+	SessionHandle string
+	SessionData   map[string]interface{}
+	JWTPayload    map[string]interface{}
+}
 
-	accessToken := getAccessTokenFromCookie(request)
-	if accessToken == nil {
-		// maybe the access token has expired.
-		return Session{}, errors.TryRefreshTokenError{
-			Msg: "access token missing in cookies",
-		}
+// Config used to set locations of SuperTokens instances
+func Config(config ConfigMap) {
+	defaultClient.config = config
+	core.GetDefaultInstance().InitQuerier(config.Hosts, config.APIKey, getQuerierOptions(config))
+	core.GetDefaultInstance().SetLogger(config.Logger)
+	core.GetDefaultInstance().SetMetrics(config.Metrics)
+	core.GetDefaultInstance().SetTracer(config.Tracer)
+	if config.SigningKey != nil {
+		core.GetDefaultInstance().SetSigningKeyOptions(*config.SigningKey)
 	}
+	// an unreadable key file is read again, and reported, when verifying a session
+	_ = core.GetDefaultInstance().SetOfflineVerification(getOfflineVerificationOptions(config))
+}
 
-	antiCsrfToken := getAntiCsrfTokenFromHeaders(request)
-
-	session, getSessionError := core.GetSession(*accessToken, antiCsrfToken, doAntiCsrfCheck)
-
-	if getSessionError != nil {
-		if errors.IsUnauthorizedError(getSessionError) {
-			handShakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
-			if handshakeInfoError != nil {
-				return Session{}, handshakeInfoError
-			}
-			clearSessionFromCookie(response,
-				handShakeInfo.CookieDomain,
-				handShakeInfo.CookieSecure,
-				handShakeInfo.AccessTokenPath,
-				handShakeInfo.RefreshTokenPath,
-				handShakeInfo.IDRefreshTokenPath,
-				handShakeInfo.CookieSameSite,
-			)
-		}
-		return Session{}, getSessionError
+func getOfflineVerificationOptions(config ConfigMap) *core.OfflineVerificationOptions {
+	if config.JWTSigningPublicKey == "" && config.JWTSigningPublicKeyPath == "" {
+		return nil
 	}
+	return &core.OfflineVerificationOptions{
+		JWTSigningPublicKey:     config.JWTSigningPublicKey,
+		JWTSigningPublicKeyPath: config.JWTSigningPublicKeyPath,
+	}
+}
 
-	if session.AccessToken != nil {
-
-		attachFrontTokenInHeaders(
-			response,
-			session.UserID,
-			session.AccessToken.Expiry,
-			session.UserDataInJWT,
-		)
-
-		attachAccessTokenToCookie(
-			response,
-			session.AccessToken.Token,
-			session.AccessToken.Expiry,
-			session.AccessToken.Domain,
-			session.AccessToken.CookieSecure,
-			session.AccessToken.CookiePath,
-			session.AccessToken.SameSite,
-		)
-		accessToken = &session.AccessToken.Token
+func getQuerierOptions(config ConfigMap) core.QuerierOptions {
+	return core.QuerierOptions{
+		HTTPClient:     config.HTTPClient,
+		RequestTimeout: config.RequestTimeout,
+		RetryPolicy:    config.RetryPolicy,
+		HealthCheck:    config.HealthCheck,
 	}
+}
 
-	return Session{
-		accessToken:   *accessToken,
-		response:      response,
-		sessionHandle: session.Handle,
-		userDataInJWT: session.UserDataInJWT,
-		userID:        session.UserID,
-	}, nil
+// CreateNewSession function used to create a new SuperTokens session
+func CreateNewSession(response http.ResponseWriter,
+	userID string, payload ...map[string]interface{}) (Session, error) {
+	return defaultClient.CreateNewSession(response, userID, payload...)
 }
 
-// RefreshSession function used to refresh a session
-func RefreshSession(response http.ResponseWriter, request *http.Request) (Session, error) {
-	saveFrontendInfoFromRequest(request)
-	inputRefreshToken := getRefreshTokenFromCookie(request)
-	if inputRefreshToken == nil {
-		return Session{}, errors.UnauthorizedError{
-			Msg: "Missing auth tokens in cookies. Have you set the correct refresh API path in your frontend and SuperTokens config?",
-		}
-	}
+// CreateNewSessionWithContext function used to create a new SuperTokens session, aborting the query to the core when ctx is done
+func CreateNewSessionWithContext(ctx context.Context, response http.ResponseWriter,
+	userID string, payload ...map[string]interface{}) (Session, error) {
+	return defaultClient.CreateNewSessionWithContext(ctx, response, userID, payload...)
+}
 
-	antiCsrfToken := getAntiCsrfTokenFromHeaders(request)
-	session, refreshError := core.RefreshSession(*inputRefreshToken, antiCsrfToken)
-
-	if refreshError != nil {
-
-		if errors.IsUnauthorizedError(refreshError) || errors.IsTokenTheftDetectedError(refreshError) {
-			handShakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
-			if handshakeInfoError != nil {
-				return Session{}, handshakeInfoError
-			}
-			clearSessionFromCookie(
-				response,
-				handShakeInfo.CookieDomain,
-				handShakeInfo.CookieSecure,
-				handShakeInfo.AccessTokenPath,
-				handShakeInfo.RefreshTokenPath,
-				handShakeInfo.IDRefreshTokenPath,
-				handShakeInfo.CookieSameSite)
-		}
-		return Session{}, refreshError
-	}
+// GetSession function used to verify a session. Queries to the core are aborted when the request's context is done
+func GetSession(response http.ResponseWriter, request *http.Request,
+	doAntiCsrfCheck bool) (Session, error) {
+	return defaultClient.GetSession(response, request, doAntiCsrfCheck)
+}
 
-	//attach cookies
-	accessToken := session.AccessToken
-	refreshToken := session.RefreshToken
-	idRefreshToken := session.IDRefreshToken
-
-	attachFrontTokenInHeaders(
-		response,
-		session.UserID,
-		accessToken.Expiry,
-		session.UserDataInJWT,
-	)
-
-	attachAccessTokenToCookie(
-		response,
-		accessToken.Token,
-		accessToken.Expiry,
-		accessToken.Domain,
-		accessToken.CookieSecure,
-		accessToken.CookiePath,
-		accessToken.SameSite,
-	)
-
-	attachRefreshTokenToCookie(
-		response,
-		refreshToken.Token,
-		refreshToken.Expiry,
-		refreshToken.Domain,
-		refreshToken.CookieSecure,
-		refreshToken.CookiePath,
-		refreshToken.SameSite,
-	)
-
-	setIDRefreshTokenInHeaderAndCookie(
-		response,
-		idRefreshToken.Token,
-		idRefreshToken.Expiry,
-		idRefreshToken.Domain,
-		idRefreshToken.CookieSecure,
-		idRefreshToken.CookiePath,
-		idRefreshToken.SameSite,
-	)
-
-	if session.AntiCsrfToken != nil {
-		setAntiCsrfTokenInHeaders(response, *session.AntiCsrfToken)
-	}
+// GetOptionalSession function used to verify a session if the request has one. It returns nil, and no error,
+// if the request has no session tokens
+func GetOptionalSession(response http.ResponseWriter, request *http.Request,
+	doAntiCsrfCheck bool) (*Session, error) {
+	return defaultClient.GetOptionalSession(response, request, doAntiCsrfCheck)
+}
 
-	return Session{
-		accessToken:   accessToken.Token,
-		sessionHandle: session.Handle,
-		userID:        session.UserID,
-		userDataInJWT: session.UserDataInJWT,
-		response:      response,
-	}, nil
+// RefreshSession function used to refresh a session. Queries to the core are aborted when the request's context is done
+func RefreshSession(response http.ResponseWriter, request *http.Request) (Session, error) {
+	return defaultClient.RefreshSession(response, request)
 }
 
 // RevokeAllSessionsForUser function used to revoke all sessions for a user
 func RevokeAllSessionsForUser(userID string) ([]string, error) {
-	return core.RevokeAllSessionsForUser(userID)
+	return defaultClient.RevokeAllSessionsForUser(userID)
+}
+
+// RevokeAllSessionsForUserWithContext function used to revoke all sessions for a user, aborting the query to the core when ctx is done
+func RevokeAllSessionsForUserWithContext(ctx context.Context, userID string) ([]string, error) {
+	return defaultClient.RevokeAllSessionsForUserWithContext(ctx, userID)
 }
 
 // GetAllSessionHandlesForUser function used to get all sessions for a user
 func GetAllSessionHandlesForUser(userID string) ([]string, error) {
-	return core.GetAllSessionHandlesForUser(userID)
+	return defaultClient.GetAllSessionHandlesForUser(userID)
+}
+
+// GetAllSessionHandlesForUserWithContext function used to get all sessions for a user, aborting the query to the core when ctx is done
+func GetAllSessionHandlesForUserWithContext(ctx context.Context, userID string) ([]string, error) {
+	return defaultClient.GetAllSessionHandlesForUserWithContext(ctx, userID)
 }
 
 // RevokeSession function used to revoke a specific session
 func RevokeSession(sessionHandle string) (bool, error) {
-	return core.RevokeSession(sessionHandle)
+	return defaultClient.RevokeSession(sessionHandle)
+}
+
+// RevokeSessionWithContext function used to revoke a specific session, aborting the query to the core when ctx is done
+func RevokeSessionWithContext(ctx context.Context, sessionHandle string) (bool, error) {
+	return defaultClient.RevokeSessionWithContext(ctx, sessionHandle)
 }
 
 // RevokeMultipleSessions function used to revoke a list of sessions
 func RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
-	return core.RevokeMultipleSessions(sessionHandles)
+	return defaultClient.RevokeMultipleSessions(sessionHandles)
+}
+
+// RevokeMultipleSessionsWithContext function used to revoke a list of sessions, aborting the query to the core when ctx is done
+func RevokeMultipleSessionsWithContext(ctx context.Context, sessionHandles []string) ([]string, error) {
+	return defaultClient.RevokeMultipleSessionsWithContext(ctx, sessionHandles)
 }
 
 // GetSessionData function used to get session data for the given handle
 func GetSessionData(sessionHandle string) (map[string]interface{}, error) {
-	return core.GetSessionData(sessionHandle)
+	return defaultClient.GetSessionData(sessionHandle)
+}
+
+// GetSessionDataWithContext function used to get session data for the given handle, aborting the query to the core when ctx is done
+func GetSessionDataWithContext(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	return defaultClient.GetSessionDataWithContext(ctx, sessionHandle)
 }
 
 // UpdateSessionData function used to update session data for the given handle
 func UpdateSessionData(sessionHandle string, newSessionData map[string]interface{}) error {
-	return core.UpdateSessionData(sessionHandle, newSessionData)
+	return defaultClient.UpdateSessionData(sessionHandle, newSessionData)
+}
+
+// UpdateSessionDataWithContext function used to update session data for the given handle, aborting the query to the core when ctx is done
+func UpdateSessionDataWithContext(ctx context.Context, sessionHandle string, newSessionData map[string]interface{}) error {
+	return defaultClient.UpdateSessionDataWithContext(ctx, sessionHandle, newSessionData)
 }
 
 // SetRelevantHeadersForOptionsAPI function is used to set headers specific to SuperTokens for OPTIONS API
 func SetRelevantHeadersForOptionsAPI(response http.ResponseWriter) {
-	setRelevantHeadersForOptionsAPI(response)
+	defaultClient.SetRelevantHeadersForOptionsAPI(response)
 }
 
 // GetCORSAllowedHeaders function is used to get header keys that are used by SuperTokens
 func GetCORSAllowedHeaders() []string {
-	return getCORSAllowedHeaders()
+	return defaultClient.GetCORSAllowedHeaders()
+}
+
+// GetCORSExposedHeaders function is used to get the keys of the response headers the frontend reads
+func GetCORSExposedHeaders() []string {
+	return defaultClient.GetCORSExposedHeaders()
 }
 
 // GetJWTPayload function used to get jwt payload for the given handle
 func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
-	return core.GetJWTPayload(sessionHandle)
+	return defaultClient.GetJWTPayload(sessionHandle)
+}
+
+// GetJWTPayloadWithContext function used to get jwt payload for the given handle, aborting the query to the core when ctx is done
+func GetJWTPayloadWithContext(ctx context.Context, sessionHandle string) (map[string]interface{}, error) {
+	return defaultClient.GetJWTPayloadWithContext(ctx, sessionHandle)
+}
+
+// GetSessionInformation function used to get the session data and jwt payload for the given handle
+func GetSessionInformation(sessionHandle string) (SessionInformation, error) {
+	return defaultClient.GetSessionInformation(sessionHandle)
+}
+
+// GetSessionInformationWithContext function used to get the session data and jwt payload for the given handle,
+// aborting the queries to the core when ctx is done
+func GetSessionInformationWithContext(ctx context.Context, sessionHandle string) (SessionInformation, error) {
+	return defaultClient.GetSessionInformationWithContext(ctx, sessionHandle)
 }
 
 // UpdateJWTPayload function used to update jwt payload for the given handle
 func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}) error {
-	return core.UpdateJWTPayload(sessionHandle, newJWTPayload)
+	return defaultClient.UpdateJWTPayload(sessionHandle, newJWTPayload)
+}
+
+// UpdateJWTPayloadWithContext function used to update jwt payload for the given handle, aborting the query to the core when ctx is done
+func UpdateJWTPayloadWithContext(ctx context.Context, sessionHandle string, newJWTPayload map[string]interface{}) error {
+	return defaultClient.UpdateJWTPayloadWithContext(ctx, sessionHandle, newJWTPayload)
 }
 
 // OnTokenTheftDetected function to override default behaviour of handling token thefts
 func OnTokenTheftDetected(handler func(string, string, http.ResponseWriter)) {
-	core.GetErrorHandlersInstance().OnTokenTheftDetectedErrorHandler = handler
+	defaultClient.OnTokenTheftDetected(handler)
 }
 
 // OnUnauthorized function to override default behaviour of handling Unauthorized error
 func OnUnauthorized(handler func(error, http.ResponseWriter)) {
-	core.GetErrorHandlersInstance().OnUnauthorizedErrorHandler = handler
+	defaultClient.OnUnauthorized(handler)
 }
 
 // OnTryRefreshToken function to override default behaviour of handling try refresh token errors
 func OnTryRefreshToken(handler func(error, http.ResponseWriter)) {
-	core.GetErrorHandlersInstance().OnTryRefreshTokenErrorHandler = handler
+	defaultClient.OnTryRefreshToken(handler)
 }
 
 // OnGeneralError function to override default behaviour of handling general errors
 func OnGeneralError(handler func(error, http.ResponseWriter)) {
-	core.GetErrorHandlersInstance().OnGeneralErrorHandler = handler
+	defaultClient.OnGeneralError(handler)
+}
+
+// OnInvalidClaim function to override default behaviour of handling sessions whose jwt payload fails claim validation
+func OnInvalidClaim(handler func(error, http.ResponseWriter)) {
+	defaultClient.OnInvalidClaim(handler)
+}
+
+// OnOfflineVerificationError function to override default behaviour of handling access tokens that cannot be verified with the pinned key
+func OnOfflineVerificationError(handler func(error, http.ResponseWriter)) {
+	defaultClient.OnOfflineVerificationError(handler)
+}
+
+// OnSigningKeyRotated function to be notified whenever the JWT signing key changes
+func OnSigningKeyRotated(handler func(SigningKeyRotation)) {
+	defaultClient.OnSigningKeyRotated(handler)
+}
+
+// GetCoreHostsStatus function used to get the health of every configured core
+func GetCoreHostsStatus() []CoreHostStatus {
+	return defaultClient.GetCoreHostsStatus()
 }
 
 // GetSessionFromRequest returns the verified session object if present, otherwise returns nil
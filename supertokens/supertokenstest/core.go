@@ -0,0 +1,548 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package supertokenstest provides an in-memory fake SuperTokens core, to test code that uses sessions
+// without running a core. Pass the fake core's URL as the Hosts of the config:
+//
+//	core := supertokenstest.NewCore(supertokenstest.Options{})
+//	defer core.Close()
+//	client, err := supertokens.New(supertokens.ConfigMap{Hosts: core.URL()})
+package supertokenstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Options configures the fake core. The zero value behaves like a core with its default config
+type Options struct {
+	// EnableAntiCsrf makes sessions have an anti-csrf token, checked when verifying access tokens
+	EnableAntiCsrf bool
+	// AccessTokenBlacklisting makes the SDK verify every access token with the core
+	AccessTokenBlacklisting bool
+	// AccessTokenValidity defaults to an hour
+	AccessTokenValidity time.Duration
+	// RefreshTokenValidity defaults to 100 days
+	RefreshTokenValidity time.Duration
+	CookieDomain         string
+	CookieSecure         bool
+	// CookieSameSite defaults to lax
+	CookieSameSite string
+	// AccessTokenPath defaults to /
+	AccessTokenPath string
+	// RefreshTokenPath defaults to /refresh
+	RefreshTokenPath string
+	// SessionExpiredStatusCode defaults to 401
+	SessionExpiredStatusCode int
+	// APIKey, if set, must be sent with every query. Other queries get a 401
+	APIKey string
+	// Now is the clock used for token expiry. Defaults to time.Now
+	Now func() time.Time
+}
+
+// Core is a fake SuperTokens core listening on a local address. It is safe for concurrent use
+type Core struct {
+	server  *httptest.Server
+	options Options
+
+	lock          sync.Mutex
+	key           signingKey
+	sessions      map[string]*session
+	refreshTokens map[string]string // hash of a valid refresh token -> session handle
+	requestCounts map[string]int
+}
+
+type session struct {
+	handle             string
+	userID             string
+	userDataInJWT      map[string]interface{}
+	userDataInDatabase map[string]interface{}
+	antiCsrfToken      string
+	expiry             time.Time
+	// refreshTokenHash is the hash of the current refresh token
+	refreshTokenHash string
+	// childRefreshTokenHash is the hash of the refresh token issued by the last refresh. It replaces the current one
+	// once it is used, either to verify an access token or to refresh
+	childRefreshTokenHash string
+	// usedRefreshTokenHashes were replaced by their child. Using one of them again means the token was stolen
+	usedRefreshTokenHashes map[string]bool
+}
+
+// NewCore starts a fake core. Like httptest.NewServer, it panics if it cannot start
+func NewCore(options Options) *Core {
+	if options.AccessTokenValidity == 0 {
+		options.AccessTokenValidity = time.Hour
+	}
+	if options.RefreshTokenValidity == 0 {
+		options.RefreshTokenValidity = 100 * 24 * time.Hour
+	}
+	if options.CookieSameSite == "" {
+		options.CookieSameSite = "lax"
+	}
+	if options.AccessTokenPath == "" {
+		options.AccessTokenPath = "/"
+	}
+	if options.RefreshTokenPath == "" {
+		options.RefreshTokenPath = "/refresh"
+	}
+	if options.SessionExpiredStatusCode == 0 {
+		options.SessionExpiredStatusCode = 401
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	key, err := newSigningKey()
+	if err != nil {
+		panic("supertokenstest: failed to generate a signing key: " + err.Error())
+	}
+	core := &Core{
+		options:       options,
+		key:           key,
+		sessions:      map[string]*session{},
+		refreshTokens: map[string]string{},
+		requestCounts: map[string]int{},
+	}
+	core.server = httptest.NewServer(http.HandlerFunc(core.serveHTTP))
+	return core
+}
+
+// URL of the fake core, to use as the Hosts of the config
+func (core *Core) URL() string {
+	return core.server.URL
+}
+
+// Close shuts down the fake core
+func (core *Core) Close() {
+	core.server.Close()
+}
+
+// JWTSigningPublicKey returns the key the access tokens are currently signed with, in the format of the
+// JWTSigningPublicKey config option
+func (core *Core) JWTSigningPublicKey() string {
+	core.lock.Lock()
+	defer core.lock.Unlock()
+	return core.key.publicKey
+}
+
+// RotateSigningKey replaces the signing key. The fake core rejects access tokens signed with the previous key
+func (core *Core) RotateSigningKey() {
+	key, err := newSigningKey()
+	if err != nil {
+		panic("supertokenstest: failed to generate a signing key: " + err.Error())
+	}
+	core.lock.Lock()
+	defer core.lock.Unlock()
+	core.key = key
+}
+
+// RequestCount returns how many queries the fake core received for the path, for example /session/verify
+func (core *Core) RequestCount(path string) int {
+	core.lock.Lock()
+	defer core.lock.Unlock()
+	return core.requestCounts[path]
+}
+
+type handlerFunc func(core *Core, body map[string]interface{}, r *http.Request) map[string]interface{}
+
+var handlers = map[string]handlerFunc{
+	"GET /apiversion":          (*Core).apiVersion,
+	"POST /handshake":          (*Core).handshake,
+	"POST /session":            (*Core).createSession,
+	"POST /session/verify":     (*Core).verifySession,
+	"POST /session/refresh":    (*Core).refreshSession,
+	"POST /session/remove":     (*Core).removeSessions,
+	"POST /session/regenerate": (*Core).regenerateSession,
+	"GET /session/user":        (*Core).getSessionHandles,
+	"GET /session/data":        (*Core).getSessionData,
+	"PUT /session/data":        (*Core).updateSessionData,
+	"GET /jwt/data":            (*Core).getJWTPayload,
+	"PUT /jwt/data":            (*Core).updateJWTPayload,
+}
+
+func (core *Core) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	core.lock.Lock()
+	defer core.lock.Unlock()
+	core.requestCounts[r.URL.Path]++
+
+	handler, ok := handlers[r.Method+" "+r.URL.Path]
+	if !ok {
+		w.WriteHeader(404)
+		w.Write([]byte("Not found"))
+		return
+	}
+	if core.options.APIKey != "" && r.Header.Get("api-key") != core.options.APIKey {
+		w.WriteHeader(401)
+		w.Write([]byte("Invalid API key"))
+		return
+	}
+	body := map[string]interface{}{}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte("Invalid JSON input"))
+			return
+		}
+	}
+	response := handler(core, body, r)
+	if response == nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Internal error"))
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (core *Core) now() time.Time {
+	return core.options.Now()
+}
+
+func getTimeInMS(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func unauthorised(message string) map[string]interface{} {
+	return map[string]interface{}{"status": "UNAUTHORISED", "message": message}
+}
+
+func tryRefreshToken(message string) map[string]interface{} {
+	return map[string]interface{}{"status": "TRY_REFRESH_TOKEN", "message": message}
+}
+
+// getSession returns the session with the handle, removing it if its refresh token has expired
+func (core *Core) getSession(handle string) *session {
+	s := core.sessions[handle]
+	if s != nil && s.expiry.Before(core.now()) {
+		core.removeSession(handle)
+		return nil
+	}
+	return s
+}
+
+func (core *Core) removeSession(handle string) {
+	delete(core.sessions, handle)
+	for hash, sessionHandle := range core.refreshTokens {
+		if sessionHandle == handle {
+			delete(core.refreshTokens, hash)
+		}
+	}
+}
+
+func (core *Core) sessionJSON(s *session) map[string]interface{} {
+	return map[string]interface{}{
+		"handle":        s.handle,
+		"userId":        s.userID,
+		"userDataInJWT": s.userDataInJWT,
+	}
+}
+
+func (core *Core) tokenJSON(token string, expiry time.Time, path string) map[string]interface{} {
+	result := map[string]interface{}{
+		"token":        token,
+		"expiry":       getTimeInMS(expiry),
+		"createdTime":  getTimeInMS(core.now()),
+		"cookiePath":   path,
+		"cookieSecure": core.options.CookieSecure,
+		"sameSite":     core.options.CookieSameSite,
+	}
+	if core.options.CookieDomain != "" {
+		result["domain"] = core.options.CookieDomain
+	}
+	return result
+}
+
+// accessTokenJSON signs a new access token for the session. parentRefreshTokenHash is empty unless the
+// token was issued by a refresh that has not been confirmed yet
+func (core *Core) accessTokenJSON(s *session, refreshTokenHash string, parentRefreshTokenHash string) map[string]interface{} {
+	now := core.now()
+	expiry := now.Add(core.options.AccessTokenValidity)
+	payload := map[string]interface{}{
+		"sessionHandle":     s.handle,
+		"userId":            s.userID,
+		"refreshTokenHash1": refreshTokenHash,
+		"userData":          s.userDataInJWT,
+		"expiryTime":        getTimeInMS(expiry),
+		"timeCreated":       getTimeInMS(now),
+	}
+	if parentRefreshTokenHash != "" {
+		payload["parentRefreshTokenHash1"] = parentRefreshTokenHash
+	}
+	if core.options.EnableAntiCsrf {
+		payload["antiCsrfToken"] = s.antiCsrfToken
+	}
+	token, err := core.key.sign(payload)
+	if err != nil {
+		return nil
+	}
+	return core.tokenJSON(token, expiry, core.options.AccessTokenPath)
+}
+
+// newTokensResponse issues a new access, refresh and id refresh token for the session. The refresh token
+// becomes the child of parentRefreshTokenHash if it is set, or else the current refresh token
+func (core *Core) newTokensResponse(s *session, parentRefreshTokenHash string) map[string]interface{} {
+	refreshToken := randomToken()
+	refreshTokenHash := hashToken(refreshToken)
+	if parentRefreshTokenHash == "" {
+		s.refreshTokenHash = refreshTokenHash
+	} else {
+		delete(core.refreshTokens, s.childRefreshTokenHash)
+		s.childRefreshTokenHash = refreshTokenHash
+	}
+	core.refreshTokens[refreshTokenHash] = s.handle
+	s.expiry = core.now().Add(core.options.RefreshTokenValidity)
+
+	accessToken := core.accessTokenJSON(s, refreshTokenHash, parentRefreshTokenHash)
+	if accessToken == nil {
+		return nil
+	}
+	response := map[string]interface{}{
+		"status":         "OK",
+		"session":        core.sessionJSON(s),
+		"accessToken":    accessToken,
+		"refreshToken":   core.tokenJSON(refreshToken, s.expiry, core.options.RefreshTokenPath),
+		"idRefreshToken": core.tokenJSON(randomToken(), s.expiry, core.options.AccessTokenPath),
+	}
+	if core.options.EnableAntiCsrf {
+		response["antiCsrfToken"] = s.antiCsrfToken
+	}
+	return response
+}
+
+// confirmChildRefreshToken makes the child refresh token the current one, after which the parent cannot be used
+func (core *Core) confirmChildRefreshToken(s *session) {
+	s.usedRefreshTokenHashes[s.refreshTokenHash] = true
+	delete(core.refreshTokens, s.refreshTokenHash)
+	s.refreshTokenHash = s.childRefreshTokenHash
+	s.childRefreshTokenHash = ""
+}
+
+func (core *Core) apiVersion(map[string]interface{}, *http.Request) map[string]interface{} {
+	return map[string]interface{}{"versions": []string{"2.3"}}
+}
+
+func (core *Core) handshake(map[string]interface{}, *http.Request) map[string]interface{} {
+	response := map[string]interface{}{
+		"status":                         "OK",
+		"jwtSigningPublicKey":            core.key.publicKey,
+		"jwtSigningPublicKeyExpiryTime":  getTimeInMS(core.now().Add(7 * 24 * time.Hour)),
+		"cookieSecure":                   core.options.CookieSecure,
+		"accessTokenPath":                core.options.AccessTokenPath,
+		"refreshTokenPath":               core.options.RefreshTokenPath,
+		"enableAntiCsrf":                 core.options.EnableAntiCsrf,
+		"accessTokenBlacklistingEnabled": core.options.AccessTokenBlacklisting,
+		"cookieSameSite":                 core.options.CookieSameSite,
+		"idRefreshTokenPath":             core.options.AccessTokenPath,
+		"sessionExpiredStatusCode":       core.options.SessionExpiredStatusCode,
+	}
+	if core.options.CookieDomain != "" {
+		response["cookieDomain"] = core.options.CookieDomain
+	}
+	return response
+}
+
+func getMap(body map[string]interface{}, key string) map[string]interface{} {
+	if value, ok := body[key].(map[string]interface{}); ok {
+		return value
+	}
+	return map[string]interface{}{}
+}
+
+func getString(body map[string]interface{}, key string) string {
+	value, _ := body[key].(string)
+	return value
+}
+
+func (core *Core) createSession(body map[string]interface{}, _ *http.Request) map[string]interface{} {
+	s := &session{
+		handle:                 randomToken(),
+		userID:                 getString(body, "userId"),
+		userDataInJWT:          getMap(body, "userDataInJWT"),
+		userDataInDatabase:     getMap(body, "userDataInDatabase"),
+		usedRefreshTokenHashes: map[string]bool{},
+	}
+	if core.options.EnableAntiCsrf {
+		s.antiCsrfToken = randomToken()
+	}
+	core.sessions[s.handle] = s
+	return core.newTokensResponse(s, "")
+}
+
+func (core *Core) verifySession(body map[string]interface{}, _ *http.Request) map[string]interface{} {
+	payload, ok := core.key.verify(getString(body, "accessToken"))
+	if !ok {
+		return tryRefreshToken("Invalid access token")
+	}
+	expiryTime, _ := payload["expiryTime"].(float64)
+	if int64(expiryTime) < getTimeInMS(core.now()) {
+		return tryRefreshToken("Access token expired")
+	}
+	s := core.getSession(getString(payload, "sessionHandle"))
+	if s == nil {
+		return unauthorised("Either the session has ended or has been blacklisted")
+	}
+	doAntiCsrfCheck, _ := body["doAntiCsrfCheck"].(bool)
+	if core.options.EnableAntiCsrf && doAntiCsrfCheck && getString(body, "antiCsrfToken") != s.antiCsrfToken {
+		return tryRefreshToken("anti-csrf check failed")
+	}
+
+	response := map[string]interface{}{
+		"status":                        "OK",
+		"session":                       core.sessionJSON(s),
+		"jwtSigningPublicKey":           core.key.publicKey,
+		"jwtSigningPublicKeyExpiryTime": getTimeInMS(core.now().Add(7 * 24 * time.Hour)),
+	}
+	response["session"].(map[string]interface{})["userDataInJWT"] = getMap(payload, "userData")
+	refreshTokenHash := getString(payload, "refreshTokenHash1")
+	if getString(payload, "parentRefreshTokenHash1") != "" {
+		// the access token of a refresh was used, so its refresh token replaces the parent
+		if refreshTokenHash == s.childRefreshTokenHash {
+			core.confirmChildRefreshToken(s)
+		}
+		if refreshTokenHash != s.refreshTokenHash {
+			return tryRefreshToken("Access token was issued by a refresh that is no longer valid")
+		}
+		accessToken := core.accessTokenJSON(s, refreshTokenHash, "")
+		if accessToken == nil {
+			return nil
+		}
+		response["session"] = core.sessionJSON(s)
+		response["accessToken"] = accessToken
+	}
+	return response
+}
+
+func (core *Core) refreshSession(body map[string]interface{}, _ *http.Request) map[string]interface{} {
+	refreshTokenHash := hashToken(getString(body, "refreshToken"))
+	var s *session
+	if handle, ok := core.refreshTokens[refreshTokenHash]; ok {
+		s = core.getSession(handle)
+	} else {
+		for _, candidate := range core.sessions {
+			if candidate.usedRefreshTokenHashes[refreshTokenHash] {
+				return map[string]interface{}{
+					"status": "TOKEN_THEFT_DETECTED",
+					"session": map[string]interface{}{
+						"handle": candidate.handle,
+						"userId": candidate.userID,
+					},
+				}
+			}
+		}
+	}
+	if s == nil {
+		return unauthorised("Refresh token not found or the session has ended")
+	}
+	if refreshTokenHash == s.childRefreshTokenHash {
+		core.confirmChildRefreshToken(s)
+	}
+	return core.newTokensResponse(s, refreshTokenHash)
+}
+
+func (core *Core) regenerateSession(body map[string]interface{}, _ *http.Request) map[string]interface{} {
+	payload, ok := core.key.verify(getString(body, "accessToken"))
+	if !ok {
+		return unauthorised("Invalid access token")
+	}
+	s := core.getSession(getString(payload, "sessionHandle"))
+	if s == nil {
+		return unauthorised("Session does not exist.")
+	}
+	s.userDataInJWT = getMap(body, "userDataInJWT")
+	accessToken := core.accessTokenJSON(s, getString(payload, "refreshTokenHash1"),
+		getString(payload, "parentRefreshTokenHash1"))
+	if accessToken == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"status":      "OK",
+		"session":     core.sessionJSON(s),
+		"accessToken": accessToken,
+	}
+}
+
+func (core *Core) removeSessions(body map[string]interface{}, _ *http.Request) map[string]interface{} {
+	var handles []string
+	if userID, ok := body["userId"].(string); ok {
+		handles = core.getSessionHandlesForUser(userID)
+	} else if sessionHandles, ok := body["sessionHandles"].([]interface{}); ok {
+		for _, handle := range sessionHandles {
+			if handle, ok := handle.(string); ok && core.getSession(handle) != nil {
+				handles = append(handles, handle)
+			}
+		}
+	}
+	revoked := []string{}
+	for _, handle := range handles {
+		core.removeSession(handle)
+		revoked = append(revoked, handle)
+	}
+	return map[string]interface{}{"status": "OK", "sessionHandlesRevoked": revoked}
+}
+
+func (core *Core) getSessionHandlesForUser(userID string) []string {
+	handles := []string{}
+	for handle, s := range core.sessions {
+		if s.userID == userID && core.getSession(handle) != nil {
+			handles = append(handles, handle)
+		}
+	}
+	sort.Strings(handles)
+	return handles
+}
+
+func (core *Core) getSessionHandles(_ map[string]interface{}, r *http.Request) map[string]interface{} {
+	return map[string]interface{}{
+		"status":         "OK",
+		"sessionHandles": core.getSessionHandlesForUser(r.URL.Query().Get("userId")),
+	}
+}
+
+func (core *Core) getSessionData(_ map[string]interface{}, r *http.Request) map[string]interface{} {
+	s := core.getSession(r.URL.Query().Get("sessionHandle"))
+	if s == nil {
+		return unauthorised("Session does not exist.")
+	}
+	return map[string]interface{}{"status": "OK", "userDataInDatabase": s.userDataInDatabase}
+}
+
+func (core *Core) updateSessionData(body map[string]interface{}, _ *http.Request) map[string]interface{} {
+	s := core.getSession(getString(body, "sessionHandle"))
+	if s == nil {
+		return unauthorised("Session does not exist.")
+	}
+	s.userDataInDatabase = getMap(body, "userDataInDatabase")
+	return map[string]interface{}{"status": "OK"}
+}
+
+func (core *Core) getJWTPayload(_ map[string]interface{}, r *http.Request) map[string]interface{} {
+	s := core.getSession(r.URL.Query().Get("sessionHandle"))
+	if s == nil {
+		return unauthorised("Session does not exist.")
+	}
+	return map[string]interface{}{"status": "OK", "userDataInJWT": s.userDataInJWT}
+}
+
+func (core *Core) updateJWTPayload(body map[string]interface{}, _ *http.Request) map[string]interface{} {
+	s := core.getSession(getString(body, "sessionHandle"))
+	if s == nil {
+		return unauthorised("Session does not exist.")
+	}
+	s.userDataInJWT = getMap(body, "userDataInJWT")
+	return map[string]interface{}{"status": "OK"}
+}
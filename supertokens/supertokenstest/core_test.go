@@ -0,0 +1,234 @@
+package supertokenstest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+	"github.com/supertokens/supertokens-go/supertokens/supertokenstest"
+)
+
+// browser keeps the cookies and anti-csrf token set by the responses, like a frontend would
+type browser struct {
+	cookies       map[string]*http.Cookie
+	antiCsrfToken string
+}
+
+func (b *browser) save(w *httptest.ResponseRecorder) {
+	for _, cookie := range w.Result().Cookies() {
+		b.cookies[cookie.Name] = cookie
+	}
+	if antiCsrfToken := w.Header().Get("anti-csrf"); antiCsrfToken != "" {
+		b.antiCsrfToken = antiCsrfToken
+	}
+}
+
+func (b *browser) request() *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range b.cookies {
+		r.AddCookie(cookie)
+	}
+	if b.antiCsrfToken != "" {
+		r.Header.Set("anti-csrf", b.antiCsrfToken)
+	}
+	return r
+}
+
+func newSession(t *testing.T, client *supertokens.Client, userID string) (*browser, supertokens.Session) {
+	w := httptest.NewRecorder()
+	session, err := client.CreateNewSession(w, userID, map[string]interface{}{"role": "admin"},
+		map[string]interface{}{"theme": "dark"})
+	assert.NoError(t, err)
+	b := &browser{cookies: map[string]*http.Cookie{}}
+	b.save(w)
+	return b, session
+}
+
+func (b *browser) getSession(client *supertokens.Client, doAntiCsrfCheck bool) (supertokens.Session, error) {
+	w := httptest.NewRecorder()
+	session, err := client.GetSession(w, b.request(), doAntiCsrfCheck)
+	b.save(w)
+	return session, err
+}
+
+func (b *browser) refreshSession(client *supertokens.Client) (supertokens.Session, error) {
+	w := httptest.NewRecorder()
+	session, err := client.RefreshSession(w, b.request())
+	b.save(w)
+	return session, err
+}
+
+func Test_Core_SessionLifecycle(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := supertokens.New(supertokens.ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	b, created := newSession(t, client, "user")
+	assert.Equal(t, "user", created.GetUserID())
+
+	session, err := b.getSession(client, true)
+	assert.NoError(t, err)
+	assert.Equal(t, created.GetHandle(), session.GetHandle())
+	assert.Equal(t, "admin", session.GetJWTPayload()["role"])
+	assert.Equal(t, 0, core.RequestCount("/session/verify"), "the token is verified locally")
+
+	oldRefreshToken := b.cookies["sRefreshToken"]
+	refreshed, err := b.refreshSession(client)
+	assert.NoError(t, err)
+	freshlyRefreshed, err := refreshed.IsFreshlyRefreshed()
+	assert.NoError(t, err)
+	assert.True(t, freshlyRefreshed)
+
+	// the first use of a refreshed access token is confirmed by the core, which issues a new one
+	session, err = b.getSession(client, true)
+	assert.NoError(t, err)
+	assert.Equal(t, created.GetHandle(), session.GetHandle())
+	freshlyRefreshed, err = session.IsFreshlyRefreshed()
+	assert.NoError(t, err)
+	assert.False(t, freshlyRefreshed)
+	assert.Equal(t, 1, core.RequestCount("/session/verify"))
+	_, err = b.getSession(client, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, core.RequestCount("/session/verify"))
+
+	stolen := &browser{cookies: map[string]*http.Cookie{"sRefreshToken": oldRefreshToken}}
+	_, err = stolen.refreshSession(client)
+	assert.True(t, errors.IsTokenTheftDetectedError(err))
+
+	revoked, err := client.RevokeSession(created.GetHandle())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = b.refreshSession(client)
+	assert.True(t, errors.IsUnauthorizedError(err))
+}
+
+func Test_Core_AntiCsrfAndBlacklisting(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{EnableAntiCsrf: true, AccessTokenBlacklisting: true})
+	defer core.Close()
+	client, err := supertokens.New(supertokens.ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	b, created := newSession(t, client, "user")
+	assert.NotEmpty(t, b.antiCsrfToken)
+	_, err = b.getSession(client, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, core.RequestCount("/session/verify"))
+
+	antiCsrfToken := b.antiCsrfToken
+	b.antiCsrfToken = "wrong"
+	_, err = b.getSession(client, true)
+	assert.True(t, errors.IsTryRefreshTokenError(err))
+	_, err = b.getSession(client, false)
+	assert.NoError(t, err)
+	b.antiCsrfToken = antiCsrfToken
+
+	_, err = client.RevokeSession(created.GetHandle())
+	assert.NoError(t, err)
+	_, err = b.getSession(client, true)
+	assert.True(t, errors.IsUnauthorizedError(err))
+}
+
+func Test_Core_ExpiryAndKeyRotation(t *testing.T) {
+	var lock sync.Mutex
+	now := time.Now()
+	core := supertokenstest.NewCore(supertokenstest.Options{
+		AccessTokenValidity: time.Minute,
+		Now: func() time.Time {
+			lock.Lock()
+			defer lock.Unlock()
+			return now
+		},
+	})
+	defer core.Close()
+	client, err := supertokens.New(supertokens.ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	b, _ := newSession(t, client, "user")
+	assert.WithinDuration(t, now.Add(time.Minute), b.cookies["sAccessToken"].Expires, time.Second)
+
+	lock.Lock()
+	now = now.Add(-2 * time.Minute)
+	lock.Unlock()
+	b, _ = newSession(t, client, "user")
+	lock.Lock()
+	now = time.Now()
+	lock.Unlock()
+	_, err = b.getSession(client, true)
+	assert.True(t, errors.IsTryRefreshTokenError(err), "the access token expired a minute ago")
+	_, err = b.refreshSession(client)
+	assert.NoError(t, err)
+	_, err = b.getSession(client, true)
+	assert.NoError(t, err)
+
+	key := core.JWTSigningPublicKey()
+	core.RotateSigningKey()
+	assert.NotEqual(t, key, core.JWTSigningPublicKey())
+	_, err = b.refreshSession(client)
+	assert.NoError(t, err)
+	verifications := core.RequestCount("/session/verify")
+	_, err = b.getSession(client, true)
+	assert.NoError(t, err)
+	assert.Equal(t, verifications+1, core.RequestCount("/session/verify"))
+	// the core sent the new key along with the new access token, so it is now verified locally
+	_, err = b.getSession(client, true)
+	assert.NoError(t, err)
+	assert.Equal(t, verifications+1, core.RequestCount("/session/verify"))
+}
+
+func Test_Core_SessionData(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{})
+	defer core.Close()
+	client, err := supertokens.New(supertokens.ConfigMap{Hosts: core.URL()})
+	assert.NoError(t, err)
+
+	_, first := newSession(t, client, "user")
+	_, second := newSession(t, client, "user")
+	newSession(t, client, "other")
+
+	information, err := client.GetSessionInformation(first.GetHandle())
+	assert.NoError(t, err)
+	assert.Equal(t, "dark", information.SessionData["theme"])
+	assert.Equal(t, "admin", information.JWTPayload["role"])
+
+	assert.NoError(t, first.UpdateSessionData(map[string]interface{}{"theme": "light"}))
+	assert.NoError(t, first.UpdateJWTPayload(map[string]interface{}{"role": "user"}))
+	assert.Equal(t, "user", first.GetJWTPayload()["role"])
+	information, err = client.GetSessionInformation(first.GetHandle())
+	assert.NoError(t, err)
+	assert.Equal(t, "light", information.SessionData["theme"])
+	assert.Equal(t, "user", information.JWTPayload["role"])
+
+	handles, err := client.GetAllSessionHandlesForUser("user")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{first.GetHandle(), second.GetHandle()}, handles)
+	revoked, err := client.RevokeAllSessionsForUser("user")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, handles, revoked)
+	handles, err = client.GetAllSessionHandlesForUser("other")
+	assert.NoError(t, err)
+	assert.Len(t, handles, 1)
+
+	_, err = client.GetSessionData(first.GetHandle())
+	assert.True(t, errors.IsUnauthorizedError(err))
+}
+
+func Test_Core_APIKey(t *testing.T) {
+	core := supertokenstest.NewCore(supertokenstest.Options{APIKey: "key"})
+	defer core.Close()
+
+	client, err := supertokens.New(supertokens.ConfigMap{Hosts: core.URL(), APIKey: "wrong"})
+	assert.NoError(t, err)
+	_, err = client.CreateNewSession(httptest.NewRecorder(), "user")
+	assert.True(t, errors.IsInvalidAPIKeyError(err))
+
+	client, err = supertokens.New(supertokens.ConfigMap{Hosts: core.URL(), APIKey: "key"})
+	assert.NoError(t, err)
+	_, err = client.CreateNewSession(httptest.NewRecorder(), "user")
+	assert.NoError(t, err)
+}
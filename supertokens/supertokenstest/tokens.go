@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokenstest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// jwtHeader is {"alg":"RS256","typ":"JWT","version":"2"}, the header of the access tokens of the core
+const jwtHeader = "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCIsInZlcnNpb24iOiIyIn0="
+
+type signingKey struct {
+	privateKey *rsa.PrivateKey
+	// publicKey is the base64 DER encoded key, in the format the core returns it
+	publicKey string
+}
+
+func newSigningKey() (signingKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return signingKey{}, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return signingKey{}, err
+	}
+	return signingKey{
+		privateKey: privateKey,
+		publicKey:  b64.StdEncoding.EncodeToString(publicKey),
+	}, nil
+}
+
+// sign returns a JWT with the payload, signed the way the core signs access tokens
+func (key signingKey) sign(payload map[string]interface{}) (string, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + b64.StdEncoding.EncodeToString(payloadJSON)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + b64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verify returns the payload of a JWT signed with this key, or false if the token is not one
+func (key signingKey) verify(token string) (map[string]interface{}, bool) {
+	splitted := strings.Split(token, ".")
+	if len(splitted) != 3 || splitted[0] != jwtHeader {
+		return nil, false
+	}
+	signature, err := b64.StdEncoding.DecodeString(splitted[2])
+	if err != nil {
+		return nil, false
+	}
+	digest := sha256.Sum256([]byte(splitted[0] + "." + splitted[1]))
+	if rsa.VerifyPKCS1v15(&key.privateKey.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+		return nil, false
+	}
+	payloadJSON, err := b64.StdEncoding.DecodeString(splitted[1])
+	if err != nil {
+		return nil, false
+	}
+	var payload map[string]interface{}
+	if json.Unmarshal(payloadJSON, &payload) != nil {
+		return nil, false
+	}
+	return payload, true
+}
+
+// randomToken returns a random opaque token, used for refresh, id refresh and anti-csrf tokens and session handles
+func randomToken() string {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}